@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+)
+
+
+
+const commandUsage = `usage:
+  chirpy                         start the server
+  chirpy bootstrap-admin <email> give an existing user the admin role`
+
+
+
+// runCommand handles one-off maintenance commands given on the command line
+// instead of starting the server.
+func runCommand(dbQueries *database.Queries, args []string) error {
+
+	switch args[0] {
+
+	case "bootstrap-admin":
+		if len(args) != 2 {
+			return errors.New(commandUsage)
+		}
+
+		user, err := dbQueries.SetUserRoleByEmail(context.Background(), database.SetUserRoleByEmailParams {
+			Email: args[1],
+			Role:  auth.RoleAdmin,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't promote %s: %w", args[1], err)
+		}
+
+		fmt.Printf("%s (%s) is now an admin\n", user.Email, user.ID)
+		return nil
+
+	default:
+		return errors.New(commandUsage)
+	}
+}
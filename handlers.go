@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"encoding/json"
@@ -8,6 +11,7 @@ import (
 	"time"
 	"github.com/DylanCoon99/bootdev-server/internal/database"
 	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/links"
 	"github.com/google/uuid"
 
 )
@@ -67,8 +71,9 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 	// get the email from the request
 
 	type request struct {
-		Password string `json:password`
+		Password string `json:"password"`
 		Email    string `json:"email"`
+		Handle   string `json:"handle"`
 	}
 
 	var req request
@@ -82,6 +87,17 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// the handle is optional at signup, it can be set later on the profile
+	handle := sql.NullString{}
+	if req.Handle != "" {
+		if msg := validateHandle(req.Handle); msg != "" {
+			w.WriteHeader(400)
+			w.Write([]byte(msg))
+			return
+		}
+		handle = sql.NullString{String: req.Handle, Valid: true}
+	}
+
 	hashed_password, err := auth.HashPassword(req.Password)
 	if err != nil {
 		w.WriteHeader(500)
@@ -93,10 +109,16 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 	params := database.CreateUserParams {
 		Email:          email,
 		HashedPassword: hashed_password,
+		Handle:         handle,
 	}
 
 
 	user, err := cfg.DBQueries.CreateUser(r.Context(), params)
+	if isUniqueViolation(err) {
+		w.WriteHeader(409)
+		w.Write([]byte("Email or handle is already taken"))
+		return
+	}
 	if err != nil {
 		// failed to create user
 		w.WriteHeader(500)
@@ -104,9 +126,9 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// encode the user to a json response
+	// encode the user to a json response, never the database row itself
 
-	b, err := json.Marshal(user)
+	b, err := json.Marshal(newUserResponse(user))
 
 	if err != nil {
 		// failed to encode user to json
@@ -122,16 +144,24 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 }
 
 
+const maxChirpLength = 140
+
+
+
 func validateChirp(body string) (statusCode int, cleaned_body string) {
+	return validateText(body, maxChirpLength)
+}
 
-	// validate the chirp
-	char_count := 0
 
-	for _ = range body {
-		char_count += 1
-	}
 
-	if char_count > 140 {
+// validateText checks the length against max and filters profanity, shared
+// by chirps and direct messages.
+func validateText(body string, max int) (statusCode int, cleaned_body string) {
+
+	// links count as a fixed length since they are shown shortened
+	char_count := links.Length(body)
+
+	if char_count > max {
 		// chirp is too long
 
 		// bad request
@@ -157,24 +187,32 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 	// get the email from the request
 
 	type request struct {
-		Body   string    `json:"body"`
-		UserId uuid.UUID `json:"user_id"`
+		Body     string      `json:"body"`
+		UserId   uuid.UUID   `json:"user_id"`
+		MediaIDs []uuid.UUID `json:"media_ids"`
+		// leave out to publish right away
+		PublishAt *time.Time `json:"publish_at"`
+		// makes this a quote chirp of another chirp
+		QuoteOf *uuid.UUID `json:"quote_of"`
+		Poll    *pollRequest `json:"poll"`
 	}
 
 	var req request
 
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		w.WriteHeader(500)
-		w.Write([]byte("Couldn't find JWT"))
-		return
-	}
+	caller, _ := principalFromContext(r.Context())
+	userID := caller.UserID
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	// suspended and banned users can't post
+	user, err := cfg.DBQueries.GetUserByID(r.Context(), userID)
 	if err != nil {
 		w.WriteHeader(401)
-		w.Write([]byte("Couldn't validate JWT"))
+		w.Write([]byte("User not found"))
+		return
+	}
+	if restriction := accountRestriction(user); restriction != "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(restriction))
 		return
 	}
 
@@ -202,14 +240,90 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	blocked, err := cfg.mentionsBlocker(r.Context(), userID, cleaned_body)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Couldn't check blocks"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(403)
+		w.Write([]byte("You can't mention a user who blocked you"))
+		return
+	}
+
+	if len(req.MediaIDs) > maxChirpMedia {
+		w.WriteHeader(400)
+		w.Write([]byte(fmt.Sprintf("A chirp can have at most %d attachments", maxChirpMedia)))
+		return
+	}
+
 
 	params := database.CreateChirpParams {
 		Body: cleaned_body,
 		UserID: userID,
 	}
 
+	if req.PublishAt != nil {
+		if !req.PublishAt.After(time.Now()) {
+			w.WriteHeader(400)
+			w.Write([]byte("publish_at must be in the future"))
+			return
+		}
+		params.PublishAt = sql.NullTime{Time: req.PublishAt.UTC(), Valid: true}
+	}
+
+	if req.QuoteOf != nil {
+		quoted, err := cfg.shareTarget(r.Context(), userID, *req.QuoteOf)
+		if err != nil {
+			w.WriteHeader(404)
+			w.Write([]byte("Quoted chirp not found"))
+			return
+		}
+		blocked, err := cfg.DBQueries.IsBlocked(r.Context(), database.IsBlockedParams {
+			BlockerID: quoted.UserID,
+			BlockedID: userID,
+		})
+		if err != nil {
+			w.WriteHeader(500)
+			w.Write([]byte("Couldn't check blocks"))
+			return
+		}
+		if blocked {
+			w.WriteHeader(403)
+			w.Write([]byte("You can't quote this user"))
+			return
+		}
+		params.QuoteOf = uuid.NullUUID{UUID: quoted.ChirpID, Valid: true}
+	}
+
+	var pollOptions []string
+	if req.Poll != nil {
+		starting := time.Now()
+		if params.PublishAt.Valid {
+			starting = params.PublishAt.Time
+		}
+		var msg string
+		pollOptions, msg = validatePoll(*req.Poll, starting)
+		if msg != "" {
+			w.WriteHeader(400)
+			w.Write([]byte(msg))
+			return
+		}
+	}
+
+	// the chirp and its attachments go in together or not at all
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
 
-	chirp, err := cfg.DBQueries.CreateChirp(r.Context(), params)
+	chirp, err := qtx.CreateChirp(r.Context(), params)
 	if err != nil {
 		// failed to create chirp
 		w.WriteHeader(500)
@@ -218,9 +332,55 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// encode the user to a json response
+	err = shortenLinks(r.Context(), qtx, chirp.ChirpID, chirp.Body)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Couldn't shorten links"))
+		return
+	}
 
-	b, err := json.Marshal(chirp)
+	err = attachMedia(r.Context(), qtx, chirp.ChirpID, userID, req.MediaIDs)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(400)
+		w.Write([]byte("Unknown or already attached media"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Couldn't attach media"))
+		return
+	}
+
+	if req.Poll != nil {
+		err = createPoll(r.Context(), qtx, chirp.ChirpID, req.Poll.ClosesAt, pollOptions)
+		if err != nil {
+			w.WriteHeader(500)
+			w.Write([]byte("Couldn't create poll"))
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Couldn't save the chirp"))
+		return
+	}
+
+	if !chirp.PublishAt.Valid {
+		cfg.chirpPublished(chirp)
+	}
+
+	// encode the chirp to a json response
+
+	res, err := cfg.chirpResponseFor(r.Context(), chirp)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to load chirp author"))
+		return
+	}
+
+	b, err := json.Marshal(res)
 
 	if err != nil {
 		// failed to encode user to json
@@ -235,6 +395,16 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 
 }
 
+
+// chirpPublished runs everything that happens once a chirp goes public,
+// either straight from createChirpHandler or later from the scheduler.
+func (cfg *apiConfig) chirpPublished(chirp database.Chirp) {
+	cfg.queueLinkPreview(chirp.Body)
+	cfg.publishChirpCreated(chirp)
+	cfg.notifyMentions(context.Background(), chirp)
+	cfg.notifyQuote(context.Background(), chirp)
+}
+
 func reverse(list []database.Chirp) []database.Chirp {
     for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
         list[i], list[j] = list[j], list[i]
@@ -259,13 +429,25 @@ func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
 
 	}
 
-	// declare a list of chirp structs
-
-	var allChirpList []database.Chirp
-
+	// ?author=<handle> is the human friendly version of author_id
+	if handle := r.URL.Query().Get("author"); handle != "" {
+		author, err := cfg.DBQueries.GetUserByHandle(r.Context(), handle)
+		if err != nil {
+			w.WriteHeader(404)
+			w.Write([]byte("Unknown author handle"))
+			return
+		}
+		userID = author.ID
+	}
 
-	// call the query function to get all chirps from the database
-	allChirpList, err = cfg.DBQueries.ListChirps(r.Context())
+	// call the query function to get all chirps from the database.
+	// hidden chirps are only listed for their author, chirps of people the
+	// caller blocked or muted not at all
+	caller, _ := principalFromContext(r.Context())
+	chirpList, err := cfg.DBQueries.ListChirps(r.Context(), database.ListChirpsParams {
+		ViewerID: caller.UserID,
+		AuthorID: uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+	})
 
 	if err != nil {
 		w.WriteHeader(500) // database failed to get chirps
@@ -273,33 +455,37 @@ func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 
-	chirpList := []database.Chirp{}
-
-	for _,dbChirp := range allChirpList {
-		if userID != uuid.Nil && dbChirp.UserID != userID {
-			continue
-		}
+	if sortParam != "" && sortParam != "asc" {
+		chirpList = reverse(chirpList)
+	}
 
-		chirpList = append(chirpList, database.Chirp{
-			ChirpID:   dbChirp.ChirpID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			UserID:    dbChirp.UserID,
-			Body:      dbChirp.Body,
+	// an author's pinned chirps go first whatever the sort order, ListChirps
+	// leaves them out in that case
+	pinned := []database.Chirp{}
+	if userID != uuid.Nil {
+		pinned, err = cfg.DBQueries.ListPinnedChirps(r.Context(), database.ListPinnedChirpsParams {
+			AuthorID: userID,
+			ViewerID: caller.UserID,
 		})
+		if err != nil {
+			w.WriteHeader(500)
+			w.Write([]byte("Failed to load pinned chirps"))
+			return
+		}
 	}
 
-
-	if sortParam == "" || sortParam == "asc" {
-		b, _ := json.Marshal(chirpList)
-
-		w.WriteHeader(200)
-		w.Write(b)
+	res, err := cfg.chirpResponses(r.Context(), append(pinned, chirpList...))
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to load chirp authors"))
 		return
-	} 
+	}
+
+	for i := range pinned {
+		res[i].Pinned = true
+	}
 
-	chirpList = reverse(chirpList)
-	b, _ := json.Marshal(chirpList)
+	b, _ := json.Marshal(res)
 
 	w.WriteHeader(200)
 	w.Write(b)
@@ -323,7 +509,21 @@ func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	b, err := json.Marshal(chirp)
+	caller, _ := principalFromContext(r.Context())
+	if !cfg.canViewChirp(r.Context(), chirp, caller) {
+		w.WriteHeader(404)
+		w.Write([]byte("Chirp not found"))
+		return
+	}
+
+	res, err := cfg.chirpResponseFor(r.Context(), chirp)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to load chirp author"))
+		return
+	}
+
+	b, err := json.Marshal(res)
 
 	w.WriteHeader(200)
 	w.Write(b)
@@ -331,28 +531,52 @@ func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 
-func (cfg apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
+// canViewChirp applies the same visibility rules as ListChirps to a single
+// chirp. Moderators can see everything.
+func (cfg *apiConfig) canViewChirp(ctx context.Context, chirp database.Chirp, caller principal) bool {
 
-	chirp_id, err := uuid.Parse(r.PathValue("chirpID"))
+	if caller.UserID == chirp.UserID {
+		return true
+	}
 
-	// authenticate the user
+	// scheduled chirps are the author's business until they go out
+	if chirp.PublishAt.Valid {
+		return false
+	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		w.WriteHeader(401)
-		w.Write([]byte("Couldn't find JWT"))
-		return
+	if auth.HasRole(caller.Claims.Role, auth.RoleModerator) {
+		return true
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		w.WriteHeader(403)
-		w.Write([]byte("Couldn't validate JWT"))
-		return
+	if chirp.HiddenAt.Valid {
+		return false
+	}
+
+	author, err := cfg.DBQueries.GetUserByID(ctx, chirp.UserID)
+	if err != nil || author.ChirpsHidden || author.DeletionRequestedAt.Valid {
+		return false
 	}
 
+	return true
+}
+
+
+func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
+
+	chirp_id, err := uuid.Parse(r.PathValue("chirpID"))
+
+	// the user was authenticated by middlewareAuth
+
+	caller, _ := principalFromContext(r.Context())
+	userID := caller.UserID
+
 	// check if the chirp belongs to this user.
 	chirp, err := cfg.DBQueries.GetChirp(r.Context(), chirp_id)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Chirp not found"))
+		return
+	}
 	if chirp.UserID != userID {
 		w.WriteHeader(403)
 		w.Write([]byte("Authentication failed"))
@@ -360,7 +584,8 @@ func (cfg apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 	}
 
 
-	// then delete the chirp
+	// then soft delete the chirp, it can be restored during the undo window
+	// and is purged for good once the retention period has passed
 	chirp, err = cfg.DBQueries.DeleteChirp(r.Context(), chirp_id)
 
 	if err != nil {
@@ -369,6 +594,11 @@ func (cfg apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// scheduled chirps never reached live clients
+	if !chirp.PublishAt.Valid {
+		cfg.publishChirpDeleted(chirp)
+	}
+
 
 	b, err := json.Marshal(chirp)
 
@@ -378,7 +608,52 @@ func (cfg apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 
 
 
-func (cfg apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) handlerRestoreChirp(w http.ResponseWriter, r *http.Request) {
+
+	chirp_id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	// only the author can undo, and only within the undo window
+	chirp, err := cfg.DBQueries.RestoreDeletedChirp(r.Context(), database.RestoreDeletedChirpParams {
+		ChirpID:      chirp_id,
+		UserID:       caller.UserID,
+		DeletedAfter: time.Now().UTC().Add(-cfg.chirpUndoWindow),
+	})
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("No deleted chirp to restore within the undo window"))
+		return
+	}
+
+	cfg.publishChirpRestored(chirp)
+
+	res, err := cfg.chirpResponseFor(r.Context(), chirp)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to load chirp author"))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to encode chirp to json"))
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(b)
+}
+
+
+
+func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 
 
 	type request struct {
@@ -416,21 +691,24 @@ func (cfg apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// only tell them about the suspension once they've proven who they are
+	if restriction := accountRestriction(user); restriction != "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(restriction))
+		return
+	}
 
-	type response struct {
-		User struct {
-			Id          string       `json:"id"`
-			CreatedAt   string       `json:"created_at"`
-			UpdatedAt   string       `json:"updated_at"`
+	// logging in during the deletion grace period keeps the account
+	if user.DeletionRequestedAt.Valid {
+		user, err = cfg.DBQueries.CancelUserDeletion(r.Context(), user.ID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Couldn't cancel account deletion"))
+			return
 		}
-		Email         string `json:"email"`
-		IsChirpyRed   bool   `json:"is_chirpy_red"`
-		Token         string `json:"token"`
-		RefreshToken  string `json:"refresh_token"`
 	}
 
 
-
 	expirationTime := time.Hour
 	if req.ExpiresInSeconds > 0 && req.ExpiresInSeconds < 3600 {
 		expirationTime = time.Duration(req.ExpiresInSeconds) * time.Second
@@ -438,6 +716,7 @@ func (cfg apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 
 	accessToken, err := auth.MakeJWT(
 		user.ID,
+		user.Role,
 		cfg.jwtSecret,
 		expirationTime,
 	)
@@ -463,18 +742,15 @@ func (cfg apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Couldn't create refresh token"))
+		return
 	}
 
 
-	var res response
-
-	res.User.Id          = (user.ID).String()
-	res.User.CreatedAt   = (user.CreatedAt).String()
-	res.User.UpdatedAt   = (user.UpdatedAt).String()
-	res.Email       = email
-	res.IsChirpyRed = user.IsChirpyRed
-	res.Token            = accessToken
-	res.RefreshToken     = refresh_token
+	res := loginResponse {
+		userResponse: newUserResponse(user),
+		Token:        accessToken,
+		RefreshToken: refresh_token,
+	}
 
 	b, err := json.Marshal(res)
 	if err != nil {
@@ -514,8 +790,15 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if restriction := accountRestriction(user); restriction != "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(restriction))
+		return
+	}
+
 	accessToken, err := auth.MakeJWT(
 		user.ID,
+		user.Role,
 		cfg.jwtSecret,
 		time.Hour,
 	)
@@ -576,18 +859,13 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 		Password string
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-
-	if err != nil {
-		w.WriteHeader(401)
-		w.Write([]byte("Couldn't find JWT"))
-		return
-	}
+	caller, _ := principalFromContext(r.Context())
+	userID := caller.UserID
 
 	var req request
 
 	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&req)
+	err := decoder.Decode(&req)
 
 	if err != nil {
 		// failed to decode request
@@ -600,18 +878,11 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 
 	
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		w.WriteHeader(401)
-		w.Write([]byte("Couldn't validate JWT"))
-		return
-	}
-
-
 	hashed_password, err := auth.HashPassword(req.Password)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Failed to hash password"))
+		return
 	}
 
 
@@ -627,29 +898,15 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		w.WriteHeader(401)
 		w.Write([]byte("Failed to update user"))
+		return
 	}
 
 
-	type response struct {
-		ID             uuid.UUID    `json:"id"`
-		CreatedAt      time.Time    `json:"created_at"`
-		UpdatedAt      time.Time    `json:"updated_at"`
-		Email          string       `json:"email"`
-		IsChirpyRed    bool         `json:"is_chirpy_red"`
-	}
-
-	var res response
-
-	res.ID        = user.ID
-	res.Email     = user.Email
-	res.CreatedAt = user.CreatedAt
-	res.UpdatedAt = user.UpdatedAt
-
-
-	b, err := json.Marshal(res)
+	b, err := json.Marshal(newUserResponse(user))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Failed to encode json response"))
+		return
 	}
 
 
@@ -710,6 +967,13 @@ func (cfg *apiConfig) handlerWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cfg.publishUserUpgraded(user)
+	cfg.notify(r.Context(), database.CreateNotificationParams {
+		UserID: user.ID,
+		Type:   notifyChirpyRed,
+		Detail: "upgraded",
+	})
+
 	w.WriteHeader(204)
 	w.Write([]byte("Upgrade successful"))
 }
\ No newline at end of file
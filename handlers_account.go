@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/DylanCoon99/bootdev-server/internal/export"
+	"github.com/google/uuid"
+)
+
+
+
+
+func (cfg *apiConfig) handlerDeleteAccount(w http.ResponseWriter, r *http.Request) {
+
+	// the account is deactivated right away and purged after the grace
+	// period, logging in again before then cancels the deletion
+
+	type request struct {
+		Password string `json:"password"`
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	user, err := cfg.DBQueries.GetUserByID(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("User not found"))
+		return
+	}
+
+	err = auth.CheckPassword(req.Password, user.HashedPassword)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Incorrect password"))
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	user, err = qtx.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams {
+		ID:          caller.UserID,
+		DeleteAfter: sql.NullTime{Time: time.Now().UTC().Add(cfg.accountDeletionGrace), Valid: true},
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't schedule account deletion"))
+		return
+	}
+
+	err = revokeUserSessions(r.Context(), qtx, caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't revoke sessions"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't commit transaction"))
+		return
+	}
+
+
+	type response struct {
+		DeleteAfter time.Time `json:"delete_after"`
+	}
+
+	b, err := json.Marshal(response{DeleteAfter: user.DeleteAfter.Time})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerExportAccount(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	count, err := cfg.DBQueries.CountChirpsByUser(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't count chirps"))
+		return
+	}
+
+	// small accounts get their archive right away
+	if count <= int64(cfg.exportSyncMaxChirps) {
+
+		archive, err := cfg.buildExportArchive(r.Context(), caller.UserID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Couldn't build export"))
+			return
+		}
+
+		// built in memory first, so a failure is a 500 rather than a
+		// truncated zip
+		var buf bytes.Buffer
+		err = export.WriteZip(&buf, archive)
+		if err != nil {
+			log.Printf("export for %s: %v", caller.UserID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Couldn't build export"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", exportDisposition(archive.GeneratedAt))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+		return
+	}
+
+	// large accounts are exported in the background, reuse a job that is
+	// already queued rather than piling up new ones
+	job, err := cfg.DBQueries.GetActiveExportJob(r.Context(), caller.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		job, err = cfg.DBQueries.CreateExportJob(r.Context(), caller.UserID)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't create export job"))
+		return
+	}
+
+	b, err := json.Marshal(job)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.Header().Set("Location", "/api/users/me/exports/" + job.ID.String())
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerGetExportJob(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	exportID, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid export ID"))
+		return
+	}
+
+	job, err := cfg.DBQueries.GetExportJob(r.Context(), database.GetExportJobParams {
+		ID:     exportID,
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Export not found"))
+		return
+	}
+
+
+	type response struct {
+		database.ExportJob
+		CompletedAt *time.Time `json:"completed_at"`
+		DownloadURL string     `json:"download_url,omitempty"`
+	}
+
+	res := response {
+		ExportJob:   job,
+		CompletedAt: nullTimePtr(job.CompletedAt),
+	}
+	if job.Status == "done" {
+		res.DownloadURL = "/api/users/me/exports/" + job.ID.String() + "/download"
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerDownloadExport(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	exportID, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid export ID"))
+		return
+	}
+
+	job, err := cfg.DBQueries.GetExportJob(r.Context(), database.GetExportJobParams {
+		ID:     exportID,
+		UserID: caller.UserID,
+	})
+	if err != nil || job.Status != "done" {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Export not ready"))
+		return
+	}
+
+	f, err := os.Open(job.FilePath)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Export has expired"))
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", exportDisposition(job.CompletedAt.Time))
+	http.ServeContent(w, r, "", job.CompletedAt.Time, f)
+}
+
+
+
+func exportDisposition(t time.Time) string {
+	return fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, t.UTC().Format("20060102-150405"))
+}
+
+
+
+// buildExportArchive collects everything stored about a user. Soft deleted
+// chirps are included since we still hold them until they are purged, and
+// conversations carry the other participants' messages too.
+func (cfg *apiConfig) buildExportArchive(ctx context.Context, userID uuid.UUID) (export.Archive, error) {
+
+	user, err := cfg.DBQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	chirps, err := cfg.DBQueries.ListChirpsByUserIncludingDeleted(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	tokens, err := cfg.DBQueries.ListRefreshTokensByUser(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	drafts, err := cfg.DBQueries.ListDraftsForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	conversations, err := cfg.DBQueries.ListConversationsForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	conversationIDs := make([]uuid.UUID, 0, len(conversations))
+	for _, conversation := range conversations {
+		conversationIDs = append(conversationIDs, conversation.ID)
+	}
+
+	participants, err := cfg.DBQueries.ListConversationParticipants(ctx, conversationIDs)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	messages, err := cfg.DBQueries.ListMessagesForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	collections, err := cfg.DBQueries.ListBookmarkCollectionsForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	bookmarks, err := cfg.DBQueries.ListBookmarksForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	votes, err := cfg.DBQueries.ListPollVotesForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	lists, err := cfg.DBQueries.ListListsForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	members, err := cfg.DBQueries.ListListMembersForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	subscriptions, err := cfg.DBQueries.ListListSubscriptionsForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	blocks, err := cfg.DBQueries.ListBlocksForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	mutes, err := cfg.DBQueries.ListMutesForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	messageBlocks, err := cfg.DBQueries.ListMessageBlocksForExport(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+
+	archive := export.Archive {
+		GeneratedAt: time.Now().UTC(),
+		Profile: export.Profile {
+			ID:          user.ID,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+			Email:       user.Email,
+			Handle:      user.Handle.String,
+			DisplayName: user.DisplayName,
+			Bio:         user.Bio,
+			AvatarURL:   user.AvatarUrl,
+			IsChirpyRed: user.IsChirpyRed,
+			Role:        user.Role,
+		},
+		Chirps:              []export.Chirp{},
+		Drafts:              []export.Draft{},
+		Sessions:            []export.Session{},
+		Conversations:       []export.Conversation{},
+		BookmarkCollections: []export.BookmarkCollection{},
+		Bookmarks:           []export.Bookmark{},
+		PollVotes:           []export.PollVote{},
+		Lists:               []export.List{},
+		ListSubscriptions:   []export.ListSubscription{},
+		Blocks:              []export.Relation{},
+		Mutes:               []export.Relation{},
+		MessageBlocks:       []export.Relation{},
+	}
+
+	for _, chirp := range chirps {
+		archive.Chirps = append(archive.Chirps, export.Chirp {
+			ID:        chirp.ChirpID,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Body:      chirp.Body,
+			DeletedAt: nullTimePtr(chirp.DeletedAt),
+		})
+	}
+
+	for _, token := range tokens {
+		archive.Sessions = append(archive.Sessions, export.Session {
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			RevokedAt: nullTimePtr(token.RevokedAt),
+		})
+	}
+
+	for _, draft := range drafts {
+		archive.Drafts = append(archive.Drafts, export.Draft {
+			ID:        draft.ID,
+			CreatedAt: draft.CreatedAt,
+			UpdatedAt: draft.UpdatedAt,
+			Body:      draft.Body,
+		})
+	}
+
+	// conversations are filled in place, so index into the slice
+	byConversation := map[uuid.UUID]int{}
+	for _, conversation := range conversations {
+		byConversation[conversation.ID] = len(archive.Conversations)
+		archive.Conversations = append(archive.Conversations, export.Conversation {
+			ID:           conversation.ID,
+			CreatedAt:    conversation.CreatedAt,
+			IsGroup:      conversation.IsGroup,
+			Participants: []uuid.UUID{},
+			Messages:     []export.Message{},
+		})
+	}
+
+	for _, participant := range participants {
+		i := byConversation[participant.ConversationID]
+		archive.Conversations[i].Participants = append(archive.Conversations[i].Participants, participant.UserID)
+	}
+
+	for _, message := range messages {
+		i := byConversation[message.ConversationID]
+		archive.Conversations[i].Messages = append(archive.Conversations[i].Messages, export.Message {
+			ID:        message.ID,
+			CreatedAt: message.CreatedAt,
+			SenderID:  message.SenderID,
+			Body:      message.Body,
+		})
+	}
+
+	for _, collection := range collections {
+		archive.BookmarkCollections = append(archive.BookmarkCollections, export.BookmarkCollection {
+			ID:        collection.ID,
+			CreatedAt: collection.CreatedAt,
+			Name:      collection.Name,
+		})
+	}
+
+	for _, bookmark := range bookmarks {
+		archive.Bookmarks = append(archive.Bookmarks, export.Bookmark {
+			ChirpID:    bookmark.ChirpID,
+			CreatedAt:  bookmark.CreatedAt,
+			Collection: bookmark.Collection.String,
+		})
+	}
+
+	for _, vote := range votes {
+		archive.PollVotes = append(archive.PollVotes, export.PollVote {
+			ChirpID:   vote.ChirpID,
+			CreatedAt: vote.CreatedAt,
+			UpdatedAt: vote.UpdatedAt,
+			Option:    vote.Option,
+		})
+	}
+
+	byList := map[uuid.UUID]int{}
+	for _, list := range lists {
+		byList[list.ID] = len(archive.Lists)
+		archive.Lists = append(archive.Lists, export.List {
+			ID:          list.ID,
+			CreatedAt:   list.CreatedAt,
+			Name:        list.Name,
+			Description: list.Description,
+			IsPrivate:   list.IsPrivate,
+			Members:     []uuid.UUID{},
+		})
+	}
+
+	for _, member := range members {
+		i := byList[member.ListID]
+		archive.Lists[i].Members = append(archive.Lists[i].Members, member.UserID)
+	}
+
+	for _, subscription := range subscriptions {
+		archive.ListSubscriptions = append(archive.ListSubscriptions, export.ListSubscription {
+			ListID:    subscription.ListID,
+			CreatedAt: subscription.CreatedAt,
+		})
+	}
+
+	for _, block := range blocks {
+		archive.Blocks = append(archive.Blocks, export.Relation{UserID: block.UserID, CreatedAt: block.CreatedAt})
+	}
+
+	for _, mute := range mutes {
+		archive.Mutes = append(archive.Mutes, export.Relation{UserID: mute.UserID, CreatedAt: mute.CreatedAt})
+	}
+
+	for _, block := range messageBlocks {
+		archive.MessageBlocks = append(archive.MessageBlocks, export.Relation{UserID: block.UserID, CreatedAt: block.CreatedAt})
+	}
+
+	return archive, nil
+}
+
+
+
+// runExportJob builds the archive for a claimed job and writes it to the
+// export directory.
+func (cfg *apiConfig) runExportJob(ctx context.Context, job database.ExportJob) error {
+
+	archive, err := cfg.buildExportArchive(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(cfg.exportDir, 0o700)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(cfg.exportDir, job.ID.String() + ".zip")
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	err = export.WriteZip(f, archive)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return cfg.DBQueries.CompleteExportJob(ctx, database.CompleteExportJobParams {
+		ID:       job.ID,
+		FilePath: path,
+	})
+}
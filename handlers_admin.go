@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+const (
+	actionSuspendUser   = "suspend_user"
+	actionUnsuspendUser = "unsuspend_user"
+	actionBanUser       = "ban_user"
+	actionUnbanUser     = "unban_user"
+)
+
+
+// accountRestriction explains why a user may not log in or post. It returns
+// an empty string for accounts in good standing.
+func accountRestriction(user database.User) string {
+
+	if user.BannedAt.Valid {
+		return "Account banned"
+	}
+
+	if user.SuspendedUntil.Valid && user.SuspendedUntil.Time.After(time.Now().UTC()) {
+		return fmt.Sprintf("Account suspended until %s", user.SuspendedUntil.Time.Format(time.RFC3339))
+	}
+
+	return ""
+}
+
+
+
+// revokeUserSessions revokes every live refresh token of a user, so they have
+// to log in again once their current access token expires.
+func revokeUserSessions(ctx context.Context, q *database.Queries, userID uuid.UUID) error {
+
+	tokens, err := q.ListActiveRefreshTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		_, err = q.RevokeRefreshToken(ctx, token.Token)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+
+
+
+func (cfg *apiConfig) handlerSetUserRole(w http.ResponseWriter, r *http.Request) {
+
+	// admins can promote or demote any user
+
+	type request struct {
+		Role string `json:"role"`
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user ID"))
+		return
+	}
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	if !auth.ValidRole(req.Role) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Role must be one of user, moderator or admin"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+	if caller.UserID == userID && req.Role != auth.RoleAdmin {
+		// stop the last admin from locking everyone out by accident
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Admins can't demote themselves"))
+		return
+	}
+
+	user, err := cfg.DBQueries.SetUserRole(r.Context(), database.SetUserRoleParams {
+		ID:   userID,
+		Role: req.Role,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("User not found"))
+		return
+	}
+
+
+	b, err := json.Marshal(newAdminUserResponse(user))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerSuspendUser(w http.ResponseWriter, r *http.Request) {
+
+	type request struct {
+		DurationSeconds int    `json:"duration_seconds"`
+		Reason          string `json:"reason"`
+	}
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	if req.DurationSeconds <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("duration_seconds must be positive"))
+		return
+	}
+
+	until := time.Now().UTC().Add(time.Duration(req.DurationSeconds) * time.Second)
+
+	cfg.restrictUser(w, r, actionSuspendUser, req.Reason, func(ctx context.Context, q *database.Queries, userID uuid.UUID) (database.User, error) {
+		return q.SuspendUser(ctx, database.SuspendUserParams {
+			ID:               userID,
+			SuspendedUntil:   sql.NullTime{Time: until, Valid: true},
+			SuspensionReason: req.Reason,
+		})
+	})
+}
+
+
+
+func (cfg *apiConfig) handlerBanUser(w http.ResponseWriter, r *http.Request) {
+
+	type request struct {
+		Reason     string `json:"reason"`
+		HideChirps bool   `json:"hide_chirps"`
+	}
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	cfg.restrictUser(w, r, actionBanUser, req.Reason, func(ctx context.Context, q *database.Queries, userID uuid.UUID) (database.User, error) {
+		return q.BanUser(ctx, database.BanUserParams {
+			ID:           userID,
+			BanReason:    req.Reason,
+			ChirpsHidden: req.HideChirps,
+		})
+	})
+}
+
+
+
+func (cfg *apiConfig) handlerUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+
+	cfg.restrictUser(w, r, actionUnsuspendUser, optionalReason(r), func(ctx context.Context, q *database.Queries, userID uuid.UUID) (database.User, error) {
+		return q.LiftSuspension(ctx, userID)
+	})
+}
+
+
+
+func (cfg *apiConfig) handlerUnbanUser(w http.ResponseWriter, r *http.Request) {
+
+	cfg.restrictUser(w, r, actionUnbanUser, optionalReason(r), func(ctx context.Context, q *database.Queries, userID uuid.UUID) (database.User, error) {
+		return q.UnbanUser(ctx, userID)
+	})
+}
+
+
+
+// restrictUser runs one of the suspend/ban updates for the {userID} in the
+// path, revokes the user's sessions when they are being restricted and writes
+// the audit record, all in one transaction.
+func (cfg *apiConfig) restrictUser(
+	w http.ResponseWriter,
+	r *http.Request,
+	action string,
+	reason string,
+	update func(context.Context, *database.Queries, uuid.UUID) (database.User, error),
+) {
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+	if caller.UserID == userID {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Admins can't restrict themselves"))
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	user, err := update(r.Context(), qtx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("User not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't update user"))
+		return
+	}
+
+	if action == actionSuspendUser || action == actionBanUser {
+		err = revokeUserSessions(r.Context(), qtx, userID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Couldn't revoke sessions"))
+			return
+		}
+	}
+
+	_, err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams {
+		ModeratorID:  uuid.NullUUID{UUID: caller.UserID, Valid: true},
+		Action:       action,
+		TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Reason:       reason,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't record moderation action"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't commit transaction"))
+		return
+	}
+
+	if action == actionBanUser && user.ChirpsHidden {
+		go cfg.publishUserChirpsDeleted(user.ID)
+	}
+
+	b, err := json.Marshal(newAdminUserResponse(user))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+// optionalReason reads {"reason": "..."} from a request body that may be empty.
+func optionalReason(r *http.Request) string {
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return ""
+	}
+
+	return req.Reason
+}
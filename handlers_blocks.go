@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+// Blocking keeps a user from interacting with the blocker and drops their
+// chirps from the blocker's views. Muting only does the latter, and the
+// muted user has no way of telling.
+
+
+
+// blockTarget reads and checks the {userID} of a block or mute request,
+// writing the error response itself when it returns false.
+func (cfg *apiConfig) blockTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user ID"))
+		return uuid.Nil, false
+	}
+
+	if userID == caller.UserID {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("You can't block or mute yourself"))
+		return uuid.Nil, false
+	}
+
+	_, err = cfg.DBQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("User not found"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+
+
+// hiddenAuthors returns the set of users whose chirps the viewer blocked or
+// muted, for live timelines that can't filter in the query.
+func (cfg *apiConfig) hiddenAuthors(ctx context.Context, viewerID uuid.UUID) (map[uuid.UUID]bool, error) {
+
+	hidden := map[uuid.UUID]bool{}
+	if viewerID == uuid.Nil {
+		return hidden, nil
+	}
+
+	ids, err := cfg.DBQueries.ListHiddenAuthors(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		hidden[id] = true
+	}
+
+	return hidden, nil
+}
+
+
+
+// mentionsBlocker reports whether body @mentions anyone who blocked the
+// author, such chirps are refused rather than posted without the mention
+// notification.
+func (cfg *apiConfig) mentionsBlocker(ctx context.Context, authorID uuid.UUID, body string) (bool, error) {
+
+	handles := findMentions(body)
+	if len(handles) == 0 {
+		return false, nil
+	}
+
+	return cfg.DBQueries.IsBlockedByAnyHandle(ctx, database.IsBlockedByAnyHandleParams {
+		BlockedID: authorID,
+		Handles:   handles,
+	})
+}
+
+
+
+
+func (cfg *apiConfig) handlerBlockUser(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	userID, ok := cfg.blockTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.DBQueries.BlockUser(r.Context(), database.BlockUserParams {
+		BlockerID: caller.UserID,
+		BlockedID: userID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't block user"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+
+func (cfg *apiConfig) handlerUnblockUser(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user ID"))
+		return
+	}
+
+	err = cfg.DBQueries.UnblockUser(r.Context(), database.UnblockUserParams {
+		BlockerID: caller.UserID,
+		BlockedID: userID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't unblock user"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+
+func (cfg *apiConfig) handlerMuteUser(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	userID, ok := cfg.blockTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.DBQueries.MuteUser(r.Context(), database.MuteUserParams {
+		MuterID: caller.UserID,
+		MutedID: userID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't mute user"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+
+func (cfg *apiConfig) handlerUnmuteUser(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user ID"))
+		return
+	}
+
+	err = cfg.DBQueries.UnmuteUser(r.Context(), database.UnmuteUserParams {
+		MuterID: caller.UserID,
+		MutedID: userID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't unmute user"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
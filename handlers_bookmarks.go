@@ -0,0 +1,456 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+// Bookmarks are private, nobody but their owner can list them. A bookmark
+// sits in at most one collection, or none.
+
+
+const maxCollectionNameLength = 50
+
+
+type bookmarkResponse struct {
+	BookmarkedAt time.Time     `json:"bookmarked_at"`
+	CollectionID uuid.NullUUID `json:"collection_id"`
+	Chirp        chirpResponse `json:"chirp"`
+}
+
+
+// collectionResponse is a collection as listed, with the number of bookmarks
+// in it.
+type collectionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Bookmarks int64     `json:"bookmarks"`
+}
+
+
+func writeBookmark(w http.ResponseWriter, status int, bookmark database.Bookmark) {
+
+	b, err := json.Marshal(bookmark)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode bookmark to json"))
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+
+// decodeCollectionName reads {"name": ...}, writing the error response
+// itself when it fails.
+func decodeCollectionName(w http.ResponseWriter, r *http.Request) (string, bool) {
+
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return "", false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxCollectionNameLength {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Collection name must be 1 to 50 characters"))
+		return "", false
+	}
+
+	return name, true
+}
+
+
+
+
+func (cfg *apiConfig) handlerBookmarkChirp(w http.ResponseWriter, r *http.Request) {
+
+	// the body is optional, {"collection_id": ...} files the bookmark
+	// straight into a collection
+
+	type request struct {
+		CollectionID uuid.NullUUID `json:"collection_id"`
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	chirp, err := cfg.DBQueries.GetChirp(r.Context(), chirpID)
+	if err != nil || !cfg.canViewChirp(r.Context(), chirp, caller) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Chirp not found"))
+		return
+	}
+
+	bookmark, err := cfg.DBQueries.CreateBookmark(r.Context(), database.CreateBookmarkParams {
+		UserID:       caller.UserID,
+		ChirpID:      chirp.ChirpID,
+		CollectionID: req.CollectionID,
+	})
+	if isUniqueViolation(err) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("You already bookmarked this chirp"))
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Collection not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't bookmark chirp"))
+		return
+	}
+
+	writeBookmark(w, http.StatusCreated, bookmark)
+}
+
+
+
+
+func (cfg *apiConfig) handlerMoveBookmark(w http.ResponseWriter, r *http.Request) {
+
+	// {"collection_id": null} takes the bookmark out of its collection
+
+	type request struct {
+		CollectionID uuid.NullUUID `json:"collection_id"`
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	bookmark, err := cfg.DBQueries.MoveBookmark(r.Context(), database.MoveBookmarkParams {
+		CollectionID: req.CollectionID,
+		UserID:       caller.UserID,
+		ChirpID:      chirpID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Bookmark or collection not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't move bookmark"))
+		return
+	}
+
+	writeBookmark(w, http.StatusOK, bookmark)
+}
+
+
+
+
+func (cfg *apiConfig) handlerDeleteBookmark(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	_, err = cfg.DBQueries.DeleteBookmark(r.Context(), database.DeleteBookmarkParams {
+		UserID:  caller.UserID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Bookmark not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+
+func (cfg *apiConfig) handlerListBookmarks(w http.ResponseWriter, r *http.Request) {
+
+	// newest first, ?collection_id= narrows it to one collection and
+	// ?before=<bookmarked_at of the last bookmark seen> fetches the next page
+
+	caller, _ := principalFromContext(r.Context())
+
+	limit, err := parseLimit(r, 20, 100)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	params := database.ListBookmarksParams {
+		UserID:     caller.UserID,
+		MaxResults: limit,
+	}
+
+	if s := r.URL.Query().Get("collection_id"); s != "" {
+		collectionID, err := uuid.Parse(s)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Invalid collection ID"))
+			return
+		}
+		params.CollectionID = uuid.NullUUID{UUID: collectionID, Valid: true}
+	}
+
+	if s := r.URL.Query().Get("before"); s != "" {
+		before, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("before must be an RFC3339 timestamp"))
+			return
+		}
+		params.Before = sql.NullTime{Time: before, Valid: true}
+	}
+
+	rows, err := cfg.DBQueries.ListBookmarks(r.Context(), params)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list bookmarks"))
+		return
+	}
+
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, row.Chirp)
+	}
+
+	chirpRes, err := cfg.chirpResponses(r.Context(), chirps)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load chirp authors"))
+		return
+	}
+
+	res := make([]bookmarkResponse, 0, len(rows))
+	for i, row := range rows {
+		res = append(res, bookmarkResponse {
+			BookmarkedAt: row.BookmarkedAt,
+			CollectionID: row.CollectionID,
+			Chirp:        chirpRes[i],
+		})
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode bookmarks to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerCreateCollection(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	name, ok := decodeCollectionName(w, r)
+	if !ok {
+		return
+	}
+
+	collection, err := cfg.DBQueries.CreateBookmarkCollection(r.Context(), database.CreateBookmarkCollectionParams {
+		UserID: caller.UserID,
+		Name:   name,
+	})
+	if isUniqueViolation(err) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("You already have a collection with that name"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't create collection"))
+		return
+	}
+
+	b, err := json.Marshal(collection)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode collection to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerListCollections(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	collections, err := cfg.DBQueries.ListBookmarkCollections(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list collections"))
+		return
+	}
+
+	res := make([]collectionResponse, 0, len(collections))
+	for _, c := range collections {
+		res = append(res, collectionResponse {
+			ID:        c.ID,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+			Name:      c.Name,
+			Bookmarks: c.Bookmarks,
+		})
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode collections to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerRenameCollection(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	collectionID, err := uuid.Parse(r.PathValue("collectionID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid collection ID"))
+		return
+	}
+
+	name, ok := decodeCollectionName(w, r)
+	if !ok {
+		return
+	}
+
+	collection, err := cfg.DBQueries.RenameBookmarkCollection(r.Context(), database.RenameBookmarkCollectionParams {
+		ID:     collectionID,
+		UserID: caller.UserID,
+		Name:   name,
+	})
+	if isUniqueViolation(err) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("You already have a collection with that name"))
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Collection not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't rename collection"))
+		return
+	}
+
+	b, err := json.Marshal(collection)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode collection to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerDeleteCollection(w http.ResponseWriter, r *http.Request) {
+
+	// the bookmarks stay, they just no longer belong to a collection
+
+	caller, _ := principalFromContext(r.Context())
+
+	collectionID, err := uuid.Parse(r.PathValue("collectionID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid collection ID"))
+		return
+	}
+
+	_, err = cfg.DBQueries.DeleteBookmarkCollection(r.Context(), database.DeleteBookmarkCollectionParams {
+		ID:     collectionID,
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Collection not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
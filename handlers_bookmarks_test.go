@@ -0,0 +1,54 @@
+package main
+
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+func TestBookmarkIntoSomeoneElsesCollection(t *testing.T) {
+
+	// CreateBookmark and MoveBookmark return no rows when the collection
+	// isn't the caller's, which the fake database does for queries it
+	// doesn't list
+	cfg, _ := newFakeConfig(t, map[string]interface{}{
+		"GetChirp": database.Chirp{},
+	})
+
+	call := func(handler http.HandlerFunc, method string) *httptest.ResponseRecorder {
+		body := `{"collection_id": "` + uuid.New().String() + `"}`
+		r := httptest.NewRequest(method, "/api/chirps/"+fakeUserID.String()+"/bookmark", strings.NewReader(body))
+		r.SetPathValue("chirpID", fakeUserID.String())
+		w := httptest.NewRecorder()
+		handler(w, asCaller(r, fakeUserID, auth.RoleUser))
+		return w
+	}
+
+	if w := call(cfg.handlerBookmarkChirp, "POST"); w.Code != 404 {
+		t.Fatalf("create: want 404, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := call(cfg.handlerMoveBookmark, "PUT"); w.Code != 404 {
+		t.Fatalf("move: want 404, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// with the collection the caller's own, both go through
+	cfg, _ = newFakeConfig(t, map[string]interface{}{
+		"GetChirp":       database.Chirp{},
+		"CreateBookmark": database.Bookmark{},
+		"MoveBookmark":   database.Bookmark{},
+	})
+
+	if w := call(cfg.handlerBookmarkChirp, "POST"); w.Code != 201 {
+		t.Fatalf("create: want 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := call(cfg.handlerMoveBookmark, "PUT"); w.Code != 200 {
+		t.Fatalf("move: want 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
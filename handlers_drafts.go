@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+// Drafts are validated like chirps but keep the body exactly as written,
+// the profanity filter only runs when a draft is published.
+
+
+// decodeDraftBody reads {"body": ...} and checks it against the chirp rules,
+// writing the error response itself when it fails.
+func decodeDraftBody(w http.ResponseWriter, r *http.Request) (string, bool) {
+
+	type request struct {
+		Body string `json:"body"`
+	}
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return "", false
+	}
+
+	status, _ := validateChirp(req.Body)
+	if status != 200 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Chirp is invalid"))
+		return "", false
+	}
+
+	return req.Body, true
+}
+
+
+func writeDraft(w http.ResponseWriter, status int, draft database.Draft) {
+
+	b, err := json.Marshal(draft)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode draft to json"))
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+
+
+func (cfg *apiConfig) handlerCreateDraft(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	body, ok := decodeDraftBody(w, r)
+	if !ok {
+		return
+	}
+
+	draft, err := cfg.DBQueries.CreateDraft(r.Context(), database.CreateDraftParams {
+		UserID: caller.UserID,
+		Body:   body,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't save the draft"))
+		return
+	}
+
+	writeDraft(w, http.StatusCreated, draft)
+}
+
+
+
+func (cfg *apiConfig) handlerListDrafts(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	limit, err := parseLimit(r, 50, 200)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	drafts, err := cfg.DBQueries.ListDrafts(r.Context(), database.ListDraftsParams {
+		UserID: caller.UserID,
+		Limit:  limit,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list drafts"))
+		return
+	}
+
+	if drafts == nil {
+		drafts = []database.Draft{}
+	}
+
+	b, err := json.Marshal(drafts)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode drafts to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+func (cfg *apiConfig) handlerGetDraft(w http.ResponseWriter, r *http.Request) {
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid draft ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	draft, err := cfg.DBQueries.GetDraft(r.Context(), database.GetDraftParams {
+		ID:     draftID,
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Draft not found"))
+		return
+	}
+
+	writeDraft(w, http.StatusOK, draft)
+}
+
+
+
+func (cfg *apiConfig) handlerUpdateDraft(w http.ResponseWriter, r *http.Request) {
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid draft ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	body, ok := decodeDraftBody(w, r)
+	if !ok {
+		return
+	}
+
+	draft, err := cfg.DBQueries.UpdateDraft(r.Context(), database.UpdateDraftParams {
+		ID:     draftID,
+		UserID: caller.UserID,
+		Body:   body,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Draft not found"))
+		return
+	}
+
+	writeDraft(w, http.StatusOK, draft)
+}
+
+
+
+func (cfg *apiConfig) handlerDeleteDraft(w http.ResponseWriter, r *http.Request) {
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid draft ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	_, err = cfg.DBQueries.DeleteDraft(r.Context(), database.DeleteDraftParams {
+		ID:     draftID,
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Draft not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+func (cfg *apiConfig) handlerPublishDraft(w http.ResponseWriter, r *http.Request) {
+
+	// the draft is deleted and the chirp created in one transaction, so
+	// publishing twice can't produce two chirps
+
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid draft ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	user, err := cfg.DBQueries.GetUserByID(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("User not found"))
+		return
+	}
+	if restriction := accountRestriction(user); restriction != "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(restriction))
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	draft, err := qtx.DeleteDraft(r.Context(), database.DeleteDraftParams {
+		ID:     draftID,
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Draft not found"))
+		return
+	}
+
+	status, cleaned_body := validateChirp(draft.Body)
+	if status != 200 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Chirp is invalid"))
+		return
+	}
+
+	blocked, err := cfg.mentionsBlocker(r.Context(), caller.UserID, cleaned_body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't check blocks"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("You can't mention a user who blocked you"))
+		return
+	}
+
+	chirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams {
+		Body:   cleaned_body,
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't create the chirp"))
+		return
+	}
+
+	err = shortenLinks(r.Context(), qtx, chirp.ChirpID, chirp.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't shorten links"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't save the chirp"))
+		return
+	}
+
+	cfg.chirpPublished(chirp)
+
+	res, err := cfg.chirpResponseFor(r.Context(), chirp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load chirp author"))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode chirp to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
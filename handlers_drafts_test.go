@@ -0,0 +1,114 @@
+package main
+
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/DylanCoon99/bootdev-server/internal/events"
+	"github.com/google/uuid"
+)
+
+
+
+func draftRequest(method, body string, userID uuid.UUID) *http.Request {
+	r := httptest.NewRequest(method, "/api/drafts/"+fakeUserID.String(), strings.NewReader(body))
+	r.SetPathValue("draftID", fakeUserID.String())
+	return asCaller(r, userID, auth.RoleUser)
+}
+
+
+// callNames lists the names of the calls, in order.
+func callNames(calls []fakeCall) string {
+	names := []string{}
+	for _, c := range calls {
+		names = append(names, c.name)
+	}
+	return strings.Join(names, " ")
+}
+
+
+
+func TestPublishDraft(t *testing.T) {
+
+	cfg, _ := newFakeConfig(t, map[string]interface{}{
+		"DeleteDraft": database.Draft{},
+		"CreateChirp": database.Chirp{},
+	})
+	cfg.events = events.NewMemory(10)
+
+	takeFakeCalls()
+	w := httptest.NewRecorder()
+	cfg.handlerPublishDraft(w, draftRequest("POST", "", fakeUserID))
+	if w.Code != 201 {
+		t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+	}
+
+	// the draft goes and the chirp comes in one transaction
+	got := callNames(takeFakeCalls())
+	if !strings.Contains(got, "BEGIN DeleteDraft CreateChirp COMMIT") {
+		t.Fatalf("want DeleteDraft and CreateChirp in one transaction, got %s", got)
+	}
+}
+
+
+
+func TestDraftsOnlyForTheirOwner(t *testing.T) {
+
+	// the draft queries match on id and user_id, so they find nothing for
+	// someone else's draft, which the fake database does for unlisted
+	// queries
+	cfg, _ := newFakeConfig(t, nil)
+	other := uuid.New()
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		body    string
+	}{
+		{"get", cfg.handlerGetDraft, "GET", ""},
+		{"update", cfg.handlerUpdateDraft, "PUT", `{"body":"mine now"}`},
+		{"delete", cfg.handlerDeleteDraft, "DELETE", ""},
+		{"publish", cfg.handlerPublishDraft, "POST", ""},
+	}
+
+	for _, c := range cases {
+		takeFakeCalls()
+		w := httptest.NewRecorder()
+		c.handler(w, draftRequest(c.method, c.body, other))
+		if w.Code != 404 {
+			t.Errorf("%s: want 404, got %d: %s", c.name, w.Code, w.Body.String())
+		}
+
+		queried := false
+		for _, call := range takeFakeCalls() {
+			if call.name == "CreateChirp" {
+				t.Errorf("%s: created a chirp from someone else's draft", c.name)
+			}
+			if strings.HasSuffix(call.name, "Draft") {
+				queried = true
+				if !argsContain(call.args, other) {
+					t.Errorf("%s: %s doesn't filter by the caller", c.name, call.name)
+				}
+			}
+		}
+		if !queried {
+			t.Errorf("%s: never looked the draft up", c.name)
+		}
+	}
+}
+
+
+func argsContain(args []driver.Value, id uuid.UUID) bool {
+	for _, a := range args {
+		if a == id.String() {
+			return true
+		}
+	}
+	return false
+}
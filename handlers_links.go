@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"github.com/google/uuid"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/DylanCoon99/bootdev-server/internal/links"
+)
+
+
+
+// Only the first link in a chirp gets a preview card, like on other
+// platforms. Previews are fetched in the background by runLinkPreviews so
+// creating a chirp never waits on somebody else's server.
+
+
+func firstURL(body string) string {
+	urls := links.FindURLs(body)
+	if len(urls) == 0 {
+		return ""
+	}
+	return urls[0]
+}
+
+
+// queueLinkPreview asks the preview workers to fetch the chirp's link. The
+// queue never blocks: if it is full the chirp simply goes without a card.
+func (cfg *apiConfig) queueLinkPreview(body string) {
+
+	u := firstURL(body)
+	if u == "" {
+		return
+	}
+
+	select {
+	case cfg.previewQueue <- u:
+	default:
+		log.Printf("link preview queue is full, skipping %s", u)
+	}
+}
+
+
+
+// fetchLinkPreview fetches and caches the preview for u unless a recent one
+// is already cached.
+func (cfg *apiConfig) fetchLinkPreview(ctx context.Context, u string) {
+
+	cached, err := cfg.DBQueries.GetLinkPreview(ctx, u)
+	if err == nil && time.Since(cached.FetchedAt) < cfg.linkPreviewTTL {
+		return
+	}
+
+	params := database.UpsertLinkPreviewParams {
+		Url:    u,
+		Status: "ok",
+	}
+
+	p, err := cfg.previewFetcher.Fetch(ctx, u)
+	if err != nil {
+		log.Printf("link preview %s: %v", u, err)
+		params.Status = "failed"
+	} else {
+		params.Title = p.Title
+		params.Description = p.Description
+		params.ImageUrl = p.ImageURL
+		params.SiteName = p.SiteName
+	}
+
+	_, err = cfg.DBQueries.UpsertLinkPreview(ctx, params)
+	if err != nil {
+		log.Printf("save link preview %s: %v", u, err)
+	}
+}
+
+
+
+// shortenLinks gives every distinct link in a new chirp a short code. The
+// body itself is stored untouched, links are swapped for their short form
+// when the chirp is rendered.
+func shortenLinks(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+
+	seen := map[string]bool{}
+	for _, u := range links.FindURLs(body) {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+
+		// retry the rare clash with an existing code
+		var err error
+		for attempt := 0; attempt < 3; attempt++ {
+			_, err = q.CreateShortLink(ctx, database.CreateShortLinkParams {
+				Code:    links.NewCode(),
+				ChirpID: chirpID,
+				Url:     u,
+			})
+			if !errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+
+func (cfg *apiConfig) shortLinkURL(code string) string {
+	return cfg.publicURL + "/l/" + code
+}
+
+
+
+func (cfg *apiConfig) handlerFollowShortLink(w http.ResponseWriter, r *http.Request) {
+
+	u, err := cfg.DBQueries.FollowShortLink(r.Context(), r.PathValue("code"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Link not found"))
+		return
+	}
+
+	http.Redirect(w, r, u, http.StatusFound)
+}
+
+
+
+func newLinkPreview(p database.LinkPreview) *links.Preview {
+	return &links.Preview{
+		URL:         p.Url,
+		Title:       p.Title,
+		Description: p.Description,
+		ImageURL:    p.ImageUrl,
+		SiteName:    p.SiteName,
+	}
+}
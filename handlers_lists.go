@@ -0,0 +1,683 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+// Lists are curated sets of users with a timeline of their own. Public lists
+// can be read and subscribed to by anyone, private ones only exist for their
+// owner, which every list query checks.
+
+
+const (
+	maxListNameLength        = 25
+	maxListDescriptionLength = 100
+	maxListMembers           = 500
+)
+
+
+type listResponse struct {
+	ID              uuid.UUID    `json:"id"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	Owner           *chirpAuthor `json:"owner"`
+	Name            string       `json:"name"`
+	Description     string       `json:"description"`
+	Private         bool         `json:"private"`
+	MemberCount     int64        `json:"member_count"`
+	SubscriberCount int64        `json:"subscriber_count"`
+	// whether the caller subscribes to the list
+	Subscribed bool `json:"subscribed"`
+}
+
+
+
+func (cfg *apiConfig) listResponses(ctx context.Context, rows []database.GetListForViewerRow) ([]listResponse, error) {
+
+	res := make([]listResponse, 0, len(rows))
+	if len(rows) == 0 {
+		return res, nil
+	}
+
+	ownerIDs := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		ownerIDs = append(ownerIDs, row.List.OwnerID)
+	}
+
+	users, err := cfg.DBQueries.ListUsersByIDs(ctx, ownerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := map[uuid.UUID]*chirpAuthor{}
+	for _, user := range users {
+		owners[user.ID] = newChirpAuthor(user)
+	}
+
+	for _, row := range rows {
+		res = append(res, listResponse {
+			ID:              row.List.ID,
+			CreatedAt:       row.List.CreatedAt,
+			UpdatedAt:       row.List.UpdatedAt,
+			Owner:           owners[row.List.OwnerID],
+			Name:            row.List.Name,
+			Description:     row.List.Description,
+			Private:         row.List.IsPrivate,
+			MemberCount:     row.MemberCount,
+			SubscriberCount: row.SubscriberCount,
+			Subscribed:      row.Subscribed,
+		})
+	}
+
+	return res, nil
+}
+
+
+
+// writeList responds with a list as the caller sees it.
+func (cfg *apiConfig) writeList(w http.ResponseWriter, r *http.Request, status int, listID uuid.UUID) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	row, err := cfg.DBQueries.GetListForViewer(r.Context(), database.GetListForViewerParams {
+		ViewerID: caller.UserID,
+		ListID:   listID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("List not found"))
+		return
+	}
+
+	res, err := cfg.listResponses(r.Context(), []database.GetListForViewerRow{row})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't load list owner"))
+		return
+	}
+
+	b, err := json.Marshal(res[0])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode list to json"))
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+
+
+type listRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+}
+
+
+// decodeList reads and checks a list, writing the error response itself
+// when it fails.
+func decodeList(w http.ResponseWriter, r *http.Request) (listRequest, bool) {
+
+	var req listRequest
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return req, false
+	}
+
+	status, name := validateText(strings.TrimSpace(req.Name), maxListNameLength)
+	if name == "" || status != 200 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("List name must be 1 to %d characters", maxListNameLength)))
+		return req, false
+	}
+	req.Name = name
+
+	status, description := validateText(strings.TrimSpace(req.Description), maxListDescriptionLength)
+	if status != 200 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("List description can be at most %d characters", maxListDescriptionLength)))
+		return req, false
+	}
+	req.Description = description
+
+	return req, true
+}
+
+
+
+
+func (cfg *apiConfig) handlerCreateList(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	req, ok := decodeList(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := cfg.DBQueries.CreateList(r.Context(), database.CreateListParams {
+		OwnerID:     caller.UserID,
+		Name:        req.Name,
+		Description: req.Description,
+		IsPrivate:   req.Private,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't create list"))
+		return
+	}
+
+	cfg.writeList(w, r, http.StatusCreated, list.ID)
+}
+
+
+
+
+func (cfg *apiConfig) handlerListLists(w http.ResponseWriter, r *http.Request) {
+
+	// the caller's own lists and the ones they subscribe to
+
+	caller, _ := principalFromContext(r.Context())
+
+	rows, err := cfg.DBQueries.ListListsForUser(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list lists"))
+		return
+	}
+
+	lists := make([]database.GetListForViewerRow, 0, len(rows))
+	for _, row := range rows {
+		lists = append(lists, database.GetListForViewerRow(row))
+	}
+
+	res, err := cfg.listResponses(r.Context(), lists)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't load list owners"))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode lists to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerGetList(w http.ResponseWriter, r *http.Request) {
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid list ID"))
+		return
+	}
+
+	cfg.writeList(w, r, http.StatusOK, listID)
+}
+
+
+
+
+func (cfg *apiConfig) handlerUpdateList(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid list ID"))
+		return
+	}
+
+	req, ok := decodeList(w, r)
+	if !ok {
+		return
+	}
+
+	_, err = cfg.DBQueries.UpdateList(r.Context(), database.UpdateListParams {
+		ID:          listID,
+		OwnerID:     caller.UserID,
+		Name:        req.Name,
+		Description: req.Description,
+		IsPrivate:   req.Private,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("List not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't update list"))
+		return
+	}
+
+	cfg.writeList(w, r, http.StatusOK, listID)
+}
+
+
+
+
+func (cfg *apiConfig) handlerDeleteList(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid list ID"))
+		return
+	}
+
+	_, err = cfg.DBQueries.DeleteList(r.Context(), database.DeleteListParams {
+		ID:      listID,
+		OwnerID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("List not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+
+func (cfg *apiConfig) handlerListListMembers(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid list ID"))
+		return
+	}
+
+	_, err = cfg.DBQueries.GetListForViewer(r.Context(), database.GetListForViewerParams {
+		ViewerID: caller.UserID,
+		ListID:   listID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("List not found"))
+		return
+	}
+
+	members, err := cfg.DBQueries.ListListMembers(r.Context(), listID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list members"))
+		return
+	}
+
+	res := make([]publicUserResponse, 0, len(members))
+	for _, member := range members {
+		res = append(res, newPublicUserResponse(member))
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode members to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerAddListMember(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid list ID"))
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user ID"))
+		return
+	}
+
+	member, err := cfg.DBQueries.GetUserByID(r.Context(), userID)
+	if err != nil || member.BannedAt.Valid || member.DeletionRequestedAt.Valid {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("User not found"))
+		return
+	}
+
+	// a block keeps you out of someone's lists
+	blocked, err := cfg.DBQueries.IsBlocked(r.Context(), database.IsBlockedParams {
+		BlockerID: userID,
+		BlockedID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't check blocks"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("You can't add this user"))
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	_, err = qtx.LockListForMembers(r.Context(), database.LockListForMembersParams {
+		ListID:  listID,
+		OwnerID: caller.UserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("List not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't lock list"))
+		return
+	}
+
+	count, err := qtx.CountListMembers(r.Context(), listID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't count members"))
+		return
+	}
+	if count >= maxListMembers {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(fmt.Sprintf("A list can have at most %d members", maxListMembers)))
+		return
+	}
+
+	added, err := qtx.AddListMember(r.Context(), database.AddListMemberParams {
+		UserID:  userID,
+		ListID:  listID,
+		OwnerID: caller.UserID,
+	})
+	if isUniqueViolation(err) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("User is already on the list"))
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("List not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't add member"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't commit transaction"))
+		return
+	}
+
+	b, err := json.Marshal(added)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode member to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerRemoveListMember(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid list ID"))
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user ID"))
+		return
+	}
+
+	_, err = cfg.DBQueries.RemoveListMember(r.Context(), database.RemoveListMemberParams {
+		ListID:  listID,
+		OwnerID: caller.UserID,
+		UserID:  userID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Member not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+
+func (cfg *apiConfig) handlerListListChirps(w http.ResponseWriter, r *http.Request) {
+
+	// newest first, ?before=<created_at of the last chirp seen> fetches the
+	// next page
+
+	caller, _ := principalFromContext(r.Context())
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid list ID"))
+		return
+	}
+
+	limit, err := parseLimit(r, 20, 100)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	params := database.ListListChirpsParams {
+		ListID:     listID,
+		ViewerID:   caller.UserID,
+		MaxResults: limit,
+	}
+
+	if s := r.URL.Query().Get("before"); s != "" {
+		before, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("before must be an RFC3339 timestamp"))
+			return
+		}
+		params.Before = sql.NullTime{Time: before, Valid: true}
+	}
+
+	_, err = cfg.DBQueries.GetListForViewer(r.Context(), database.GetListForViewerParams {
+		ViewerID: caller.UserID,
+		ListID:   listID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("List not found"))
+		return
+	}
+
+	chirps, err := cfg.DBQueries.ListListChirps(r.Context(), params)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list chirps"))
+		return
+	}
+
+	res, err := cfg.chirpResponses(r.Context(), chirps)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load chirp authors"))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode chirps to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerSubscribeToList(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid list ID"))
+		return
+	}
+
+	row, err := cfg.DBQueries.GetListForViewer(r.Context(), database.GetListForViewerParams {
+		ViewerID: caller.UserID,
+		ListID:   listID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("List not found"))
+		return
+	}
+
+	if row.List.OwnerID == caller.UserID {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("You can't subscribe to your own list"))
+		return
+	}
+
+	blocked, err := cfg.DBQueries.IsBlocked(r.Context(), database.IsBlockedParams {
+		BlockerID: row.List.OwnerID,
+		BlockedID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't check blocks"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("You can't subscribe to this list"))
+		return
+	}
+
+	// the query checks again that the list is public, it may have been made
+	// private in the meantime
+	_, err = cfg.DBQueries.SubscribeToList(r.Context(), database.SubscribeToListParams {
+		UserID: caller.UserID,
+		ListID: listID,
+	})
+	if isUniqueViolation(err) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("You already subscribe to this list"))
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("List not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't subscribe to list"))
+		return
+	}
+
+	cfg.writeList(w, r, http.StatusCreated, listID)
+}
+
+
+
+
+func (cfg *apiConfig) handlerUnsubscribeFromList(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid list ID"))
+		return
+	}
+
+	_, err = cfg.DBQueries.UnsubscribeFromList(r.Context(), database.UnsubscribeFromListParams {
+		ListID: listID,
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("You don't subscribe to this list"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,72 @@
+package main
+
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+func TestDecodeList(t *testing.T) {
+
+	cases := []struct {
+		body     string
+		ok       bool
+		wantName string
+		wantDesc string
+	}{
+		{`{"name":" Go devs ","description":"people writing Go","private":true}`, true, "Go devs", "people writing Go"},
+		{`{"name":"fornax fans","description":"no fornax here"}`, true, "**** fans", "no **** here"},
+		{`{"name":"   "}`, false, "", ""},
+		{`{"name":"` + strings.Repeat("a", maxListNameLength+1) + `"}`, false, "", ""},
+		{`{"name":"ok","description":"` + strings.Repeat("a", maxListDescriptionLength+1) + `"}`, false, "", ""},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req, ok := decodeList(w, httptest.NewRequest("POST", "/api/lists", strings.NewReader(c.body)))
+		if ok != c.ok {
+			t.Errorf("%s: got ok %v, want %v (%s)", c.body, ok, c.ok, w.Body.String())
+			continue
+		}
+		if ok && (req.Name != c.wantName || req.Description != c.wantDesc) {
+			t.Errorf("%s: got %q %q, want %q %q", c.body, req.Name, req.Description, c.wantName, c.wantDesc)
+		}
+	}
+}
+
+
+
+func TestPrivateListHiddenFromOthers(t *testing.T) {
+
+	// the fake database has members and chirps, but GetListForViewer finds
+	// no list, as it does for a private list viewed by someone else
+	cfg, _ := newFakeConfig(t, map[string]interface{}{
+		"ListListMembers": database.User{},
+		"ListListChirps":  database.Chirp{},
+	})
+
+	viewer := uuid.New()
+	for _, c := range []struct {
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"members", cfg.handlerListListMembers},
+		{"chirps", cfg.handlerListListChirps},
+	} {
+		r := httptest.NewRequest("GET", "/api/lists/"+fakeUserID.String()+"/"+c.path, nil)
+		r.SetPathValue("listID", fakeUserID.String())
+
+		w := httptest.NewRecorder()
+		c.handler(w, asCaller(r, viewer, auth.RoleUser))
+		if w.Code != 404 {
+			t.Errorf("%s: want 404, got %d: %s", c.path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/DylanCoon99/bootdev-server/internal/media"
+	"github.com/google/uuid"
+)
+
+
+
+// a chirp can carry this many attachments
+const maxChirpMedia = 4
+
+
+type mediaResponse struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Width       int32     `json:"width"`
+	Height      int32     `json:"height"`
+}
+
+
+func (cfg *apiConfig) newMediaResponse(m database.Media) mediaResponse {
+	return mediaResponse {
+		ID:          m.ID,
+		URL:         cfg.mediaStore.URL(m.StorageKey),
+		ContentType: m.ContentType,
+		SizeBytes:   m.SizeBytes,
+		Width:       m.Width,
+		Height:      m.Height,
+	}
+}
+
+
+
+
+func (cfg *apiConfig) handlerUploadMedia(w http.ResponseWriter, r *http.Request) {
+
+	// multipart upload with the file in the "file" field, the returned id is
+	// then passed in media_ids when creating a chirp
+
+	caller, _ := principalFromContext(r.Context())
+
+	user, err := cfg.DBQueries.GetUserByID(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("User not found"))
+		return
+	}
+	if restriction := accountRestriction(user); restriction != "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(restriction))
+		return
+	}
+
+	// leave some room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.mediaMaxBytes+64<<10)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			w.Write([]byte("File is too large"))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Missing file"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, cfg.mediaMaxBytes+1))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to read the file"))
+		return
+	}
+	if int64(len(data)) > cfg.mediaMaxBytes {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte("File is too large"))
+		return
+	}
+
+	info, cleaned, err := media.Process(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		w.Write([]byte("Only JPEG, PNG and GIF images are supported"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("File is not a valid image"))
+		return
+	}
+
+	id := uuid.New()
+	key := id.String() + info.Extension
+
+	err = cfg.mediaStore.Put(r.Context(), key, bytes.NewReader(cleaned))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't store the file"))
+		return
+	}
+
+	m, err := cfg.DBQueries.CreateMedia(r.Context(), database.CreateMediaParams {
+		ID:          id,
+		UserID:      caller.UserID,
+		StorageKey:  key,
+		ContentType: info.ContentType,
+		SizeBytes:   int64(len(cleaned)),
+		Width:       int32(info.Width),
+		Height:      int32(info.Height),
+	})
+	if err != nil {
+		cfg.mediaStore.Delete(context.Background(), key)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't save the upload"))
+		return
+	}
+
+	b, err := json.Marshal(cfg.newMediaResponse(m))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode media to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
+
+
+
+// attachMedia links uploads to a freshly created chirp in the order given.
+// It fails if any of them doesn't exist, belongs to someone else or is
+// already attached.
+func attachMedia(ctx context.Context, q *database.Queries, chirpID, userID uuid.UUID, mediaIDs []uuid.UUID) error {
+	for i, id := range mediaIDs {
+		_, err := q.AttachMedia(ctx, database.AttachMediaParams {
+			ChirpID:  uuid.NullUUID{UUID: chirpID, Valid: true},
+			Position: int32(i),
+			ID:       id,
+			UserID:   userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+
+
+
+
+func (cfg *apiConfig) handlerServeMedia(w http.ResponseWriter, r *http.Request) {
+
+	// a file is only served to those who can see the chirp it is attached
+	// to, unattached uploads only to the uploader
+
+	caller, _ := principalFromContext(r.Context())
+
+	m, err := cfg.DBQueries.GetMediaByStorageKey(r.Context(), r.PathValue("key"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !m.ChirpID.Valid {
+		if m.UserID != caller.UserID {
+			http.NotFound(w, r)
+			return
+		}
+	} else {
+		chirp, err := cfg.DBQueries.GetChirp(r.Context(), m.ChirpID.UUID)
+		if err != nil || !cfg.canViewChirp(r.Context(), chirp, caller) {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	f, err := cfg.mediaStore.Open(r.Context(), m.StorageKey)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", m.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(m.SizeBytes, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
@@ -0,0 +1,53 @@
+package main
+
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/DylanCoon99/bootdev-server/internal/media"
+	"github.com/google/uuid"
+)
+
+
+
+func TestServeMediaOnlyToUploaderUntilAttached(t *testing.T) {
+
+	// the fake upload belongs to fakeUserID, has key "x" and no chirp yet
+	cfg, _ := newFakeConfig(t, map[string]interface{}{
+		"GetMediaByStorageKey": database.Media{},
+	})
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "x"), []byte("y"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.mediaStore = media.NewFSStore(dir, "/media/")
+
+	get := func(userID uuid.UUID) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/media/x", nil)
+		r.SetPathValue("key", "x")
+		if userID != uuid.Nil {
+			r = asCaller(r, userID, auth.RoleUser)
+		}
+		w := httptest.NewRecorder()
+		cfg.handlerServeMedia(w, r)
+		return w
+	}
+
+	w := get(fakeUserID)
+	if w.Code != 200 || w.Body.String() != "y" {
+		t.Fatalf("uploader: got %d %q", w.Code, w.Body.String())
+	}
+
+	if w := get(uuid.New()); w.Code != 404 {
+		t.Fatalf("someone else: want 404, got %d", w.Code)
+	}
+	if w := get(uuid.Nil); w.Code != 404 {
+		t.Fatalf("anonymous: want 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,764 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+const (
+	maxMessageLength = 1000
+	// including whoever started the conversation
+	maxConversationSize = 10
+)
+
+
+
+type messageResponse struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+
+func newMessageResponse(m database.Message) messageResponse {
+	return messageResponse {
+		ID:             m.ID,
+		CreatedAt:      m.CreatedAt,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+	}
+}
+
+
+// conversationParticipant doubles as the read receipt, everything sent
+// before last_read_at has been seen by that user.
+type conversationParticipant struct {
+	User       *chirpAuthor `json:"user"`
+	LastReadAt *time.Time   `json:"last_read_at"`
+}
+
+
+type conversationResponse struct {
+	ID           uuid.UUID                 `json:"id"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+	IsGroup      bool                      `json:"is_group"`
+	Participants []conversationParticipant `json:"participants"`
+	LastMessage  *messageResponse          `json:"last_message"`
+	UnreadCount  int64                     `json:"unread_count"`
+}
+
+
+
+// conversationResponses loads participants and the latest message of each
+// conversation in one go.
+func (cfg *apiConfig) conversationResponses(ctx context.Context, conversations []database.ListConversationsForUserRow) ([]conversationResponse, error) {
+
+	res := make([]conversationResponse, 0, len(conversations))
+	if len(conversations) == 0 {
+		return res, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(conversations))
+	for _, c := range conversations {
+		ids = append(ids, c.ID)
+	}
+
+	participants, err := cfg.DBQueries.ListConversationParticipants(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(participants))
+	for _, p := range participants {
+		userIDs = append(userIDs, p.UserID)
+	}
+
+	users, err := cfg.DBQueries.ListUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	authors := map[uuid.UUID]*chirpAuthor{}
+	for _, user := range users {
+		authors[user.ID] = newChirpAuthor(user)
+	}
+
+	byConversation := map[uuid.UUID][]conversationParticipant{}
+	for _, p := range participants {
+		byConversation[p.ConversationID] = append(byConversation[p.ConversationID], conversationParticipant {
+			User:       authors[p.UserID],
+			LastReadAt: nullTimePtr(p.LastReadAt),
+		})
+	}
+
+	lastMessages, err := cfg.DBQueries.ListLastMessages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	last := map[uuid.UUID]*messageResponse{}
+	for _, m := range lastMessages {
+		message := newMessageResponse(m)
+		last[m.ConversationID] = &message
+	}
+
+	for _, c := range conversations {
+		res = append(res, conversationResponse {
+			ID:           c.ID,
+			CreatedAt:    c.CreatedAt,
+			UpdatedAt:    c.UpdatedAt,
+			IsGroup:      c.IsGroup,
+			Participants: byConversation[c.ID],
+			LastMessage:  last[c.ID],
+			UnreadCount:  c.UnreadCount,
+		})
+	}
+
+	return res, nil
+}
+
+
+
+// conversationResponseFor renders a single conversation as userID sees it.
+func (cfg *apiConfig) conversationResponseFor(ctx context.Context, c database.Conversation, userID uuid.UUID) (conversationResponse, error) {
+
+	unread, err := cfg.DBQueries.CountUnreadMessages(ctx, database.CountUnreadMessagesParams {
+		UserID:         userID,
+		ConversationID: c.ID,
+	})
+	if err != nil {
+		return conversationResponse{}, err
+	}
+
+	res, err := cfg.conversationResponses(ctx, []database.ListConversationsForUserRow{{
+		ID:          c.ID,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+		CreatedBy:   c.CreatedBy,
+		IsGroup:     c.IsGroup,
+		UnreadCount: unread,
+	}})
+	if err != nil {
+		return conversationResponse{}, err
+	}
+
+	return res[0], nil
+}
+
+
+
+func (cfg *apiConfig) writeConversation(w http.ResponseWriter, r *http.Request, status int, c database.Conversation) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	res, err := cfg.conversationResponseFor(r.Context(), c, caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't load conversation"))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerCreateConversation(w http.ResponseWriter, r *http.Request) {
+
+	// one other participant makes a direct conversation, which is reused if
+	// it already exists, more than one makes a group
+
+	type request struct {
+		ParticipantIDs []uuid.UUID `json:"participant_ids"`
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	seen := map[uuid.UUID]bool{caller.UserID: true}
+	others := []uuid.UUID{}
+	for _, id := range req.ParticipantIDs {
+		if !seen[id] {
+			seen[id] = true
+			others = append(others, id)
+		}
+	}
+
+	if len(others) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("A conversation needs at least one other participant"))
+		return
+	}
+
+	if len(others) + 1 > maxConversationSize {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("A conversation can have at most %d participants", maxConversationSize)))
+		return
+	}
+
+	user, err := cfg.DBQueries.GetUserByID(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("User not found"))
+		return
+	}
+
+	if restriction := accountRestriction(user); restriction != "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(restriction))
+		return
+	}
+
+	users, err := cfg.DBQueries.ListUsersByIDs(r.Context(), others)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't look up participants"))
+		return
+	}
+
+	found := 0
+	for _, u := range users {
+		if !u.BannedAt.Valid && !u.DeleteAfter.Valid {
+			found++
+		}
+	}
+	if found != len(others) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Participant not found"))
+		return
+	}
+
+	blocked, err := cfg.DBQueries.IsBlockedByAny(r.Context(), database.IsBlockedByAnyParams {
+		UserIds:  others,
+		SenderID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't check blocks"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("You can't message this user"))
+		return
+	}
+
+	isGroup := len(others) > 1
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	if !isGroup {
+		// two requests for the same pair must not both miss the lookup and
+		// create a conversation each, so the lookup runs under a lock
+		pair := database.LockDirectConversationParams {
+			UserA: caller.UserID,
+			UserB: others[0],
+		}
+		err = qtx.LockDirectConversation(r.Context(), pair)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Couldn't lock conversation"))
+			return
+		}
+
+		existing, err := qtx.FindDirectConversation(r.Context(), database.FindDirectConversationParams {
+			UserA: pair.UserA,
+			UserB: pair.UserB,
+		})
+		if err == nil {
+			tx.Rollback()
+			cfg.writeConversation(w, r, http.StatusOK, existing)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Couldn't look up conversation"))
+			return
+		}
+	}
+
+	conversation, err := qtx.CreateConversation(r.Context(), database.CreateConversationParams {
+		CreatedBy: uuid.NullUUID{UUID: caller.UserID, Valid: true},
+		IsGroup:   isGroup,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't create conversation"))
+		return
+	}
+
+	for _, id := range append([]uuid.UUID{caller.UserID}, others...) {
+		err = qtx.AddConversationParticipant(r.Context(), database.AddConversationParticipantParams {
+			ConversationID: conversation.ID,
+			UserID:         id,
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Couldn't add participant"))
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't commit transaction"))
+		return
+	}
+
+	cfg.writeConversation(w, r, http.StatusCreated, conversation)
+}
+
+
+
+
+func (cfg *apiConfig) handlerListConversations(w http.ResponseWriter, r *http.Request) {
+
+	// most recently active first, ?before=<updated_at of the last
+	// conversation seen> fetches the next page
+
+	caller, _ := principalFromContext(r.Context())
+
+	limit, err := parseLimit(r, 20, 100)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	params := database.ListConversationsForUserParams {
+		UserID:     caller.UserID,
+		MaxResults: limit,
+	}
+
+	if s := r.URL.Query().Get("before"); s != "" {
+		before, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("before must be an RFC3339 timestamp"))
+			return
+		}
+		params.Before = sql.NullTime{Time: before, Valid: true}
+	}
+
+	conversations, err := cfg.DBQueries.ListConversationsForUser(r.Context(), params)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list conversations"))
+		return
+	}
+
+	res, err := cfg.conversationResponses(r.Context(), conversations)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't load conversations"))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode conversations to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerGetConversation(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid conversation ID"))
+		return
+	}
+
+	conversation, err := cfg.DBQueries.GetConversationForUser(r.Context(), database.GetConversationForUserParams {
+		ConversationID: conversationID,
+		UserID:         caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Conversation not found"))
+		return
+	}
+
+	cfg.writeConversation(w, r, http.StatusOK, conversation)
+}
+
+
+
+
+func (cfg *apiConfig) handlerSendMessage(w http.ResponseWriter, r *http.Request) {
+
+	type request struct {
+		Body string `json:"body"`
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid conversation ID"))
+		return
+	}
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	if strings.TrimSpace(req.Body) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Message is empty"))
+		return
+	}
+
+	statusCode, cleaned_body := validateText(req.Body, maxMessageLength)
+	if statusCode != 200 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Message is too long"))
+		return
+	}
+
+	user, err := cfg.DBQueries.GetUserByID(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("User not found"))
+		return
+	}
+
+	if restriction := accountRestriction(user); restriction != "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(restriction))
+		return
+	}
+
+	_, err = cfg.DBQueries.GetConversationForUser(r.Context(), database.GetConversationForUserParams {
+		ConversationID: conversationID,
+		UserID:         caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Conversation not found"))
+		return
+	}
+
+	blocked, err := cfg.DBQueries.IsBlockedFromConversation(r.Context(), database.IsBlockedFromConversationParams {
+		ConversationID: conversationID,
+		SenderID:       caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't check blocks"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("You can't message this conversation"))
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	message, err := qtx.CreateMessage(r.Context(), database.CreateMessageParams {
+		Body:           cleaned_body,
+		ConversationID: conversationID,
+		SenderID:       caller.UserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// left the conversation in the meantime
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Conversation not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't send message"))
+		return
+	}
+
+	err = qtx.TouchConversation(r.Context(), conversationID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't update conversation"))
+		return
+	}
+
+	// the sender has obviously seen their own message
+	_, err = qtx.MarkConversationRead(r.Context(), database.MarkConversationReadParams {
+		ConversationID: conversationID,
+		UserID:         caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't update read receipt"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't commit transaction"))
+		return
+	}
+
+	b, err := json.Marshal(newMessageResponse(message))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerListMessages(w http.ResponseWriter, r *http.Request) {
+
+	// newest first, ?before=<created_at of the oldest message seen> fetches
+	// older history
+
+	caller, _ := principalFromContext(r.Context())
+
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid conversation ID"))
+		return
+	}
+
+	limit, err := parseLimit(r, 50, 200)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	params := database.ListMessagesParams {
+		ConversationID: conversationID,
+		UserID:         caller.UserID,
+		MaxResults:     limit,
+	}
+
+	if s := r.URL.Query().Get("before"); s != "" {
+		before, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("before must be an RFC3339 timestamp"))
+			return
+		}
+		params.Before = sql.NullTime{Time: before, Valid: true}
+	}
+
+	// an empty page can't tell a stranger from a quiet conversation
+	_, err = cfg.DBQueries.GetConversationForUser(r.Context(), database.GetConversationForUserParams {
+		ConversationID: conversationID,
+		UserID:         caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Conversation not found"))
+		return
+	}
+
+	messages, err := cfg.DBQueries.ListMessages(r.Context(), params)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list messages"))
+		return
+	}
+
+	res := make([]messageResponse, 0, len(messages))
+	for _, m := range messages {
+		res = append(res, newMessageResponse(m))
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode messages to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerMarkConversationRead(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid conversation ID"))
+		return
+	}
+
+	participant, err := cfg.DBQueries.MarkConversationRead(r.Context(), database.MarkConversationReadParams {
+		ConversationID: conversationID,
+		UserID:         caller.UserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Conversation not found"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't mark conversation read"))
+		return
+	}
+
+	type response struct {
+		LastReadAt time.Time `json:"last_read_at"`
+	}
+
+	b, err := json.Marshal(response{LastReadAt: participant.LastReadAt.Time})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerBlockMessages(w http.ResponseWriter, r *http.Request) {
+
+	// stops the user from starting conversations with the caller or posting
+	// in ones the caller is part of
+
+	caller, _ := principalFromContext(r.Context())
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user ID"))
+		return
+	}
+
+	if userID == caller.UserID {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("You can't block yourself"))
+		return
+	}
+
+	_, err = cfg.DBQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("User not found"))
+		return
+	}
+
+	err = cfg.DBQueries.BlockMessagesFrom(r.Context(), database.BlockMessagesFromParams {
+		BlockerID: caller.UserID,
+		BlockedID: userID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't block user"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+
+func (cfg *apiConfig) handlerUnblockMessages(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user ID"))
+		return
+	}
+
+	err = cfg.DBQueries.UnblockMessagesFrom(r.Context(), database.UnblockMessagesFromParams {
+		BlockerID: caller.UserID,
+		BlockedID: userID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't unblock user"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
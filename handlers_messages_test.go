@@ -0,0 +1,66 @@
+package main
+
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+)
+
+
+
+func TestValidateMessageLength(t *testing.T) {
+
+	cases := []struct {
+		body       string
+		max        int
+		wantStatus int
+		wantBody   string
+	}{
+		{strings.Repeat("a", 140), maxChirpLength, 200, strings.Repeat("a", 140)},
+		{strings.Repeat("a", 141), maxChirpLength, 400, ""},
+		// too long for a chirp is fine for a message
+		{strings.Repeat("a", 141), maxMessageLength, 200, strings.Repeat("a", 141)},
+		{strings.Repeat("a", 1001), maxMessageLength, 400, ""},
+		{"what a kerfuffle", maxMessageLength, 200, "what a ****"},
+	}
+
+	for _, c := range cases {
+		status, body := validateText(c.body, c.max)
+		if status != c.wantStatus || body != c.wantBody {
+			t.Errorf("validateText(%d chars, %d) = %d %q, want %d %q", len(c.body), c.max, status, body, c.wantStatus, c.wantBody)
+		}
+	}
+}
+
+
+
+func TestGetConversationCountsUnread(t *testing.T) {
+
+	// the fake database has the conversation and one unread message in it
+	cfg, _ := newFakeConfig(t, map[string]interface{}{
+		"GetConversationForUser": database.Conversation{},
+		"CountUnreadMessages":    struct{ Count int64 }{},
+	})
+
+	r := httptest.NewRequest("GET", "/api/conversations/"+fakeUserID.String(), nil)
+	r.SetPathValue("conversationID", fakeUserID.String())
+
+	w := httptest.NewRecorder()
+	cfg.handlerGetConversation(w, asCaller(r, fakeUserID, auth.RoleUser))
+	if w.Code != 200 {
+		t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+	}
+
+	var res conversationResponse
+	err := json.Unmarshal(w.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.UnreadCount != 1 {
+		t.Fatalf("unread_count = %d, want 1", res.UnreadCount)
+	}
+}
@@ -0,0 +1,615 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"misinformation": true,
+	"other":          true,
+}
+
+
+const (
+	actionAutoHide      = "auto_hide_chirp"
+	actionHideChirp     = "hide_chirp"
+	actionRestoreChirp  = "restore_chirp"
+	actionDismissReport = "dismiss_report"
+)
+
+
+type reportResponse struct {
+	ID         uuid.UUID     `json:"id"`
+	CreatedAt  time.Time     `json:"created_at"`
+	ChirpID    uuid.UUID     `json:"chirp_id"`
+	ReporterID uuid.UUID     `json:"reporter_id"`
+	Reason     string        `json:"reason"`
+	Details    string        `json:"details"`
+	Status     string        `json:"status"`
+	ResolvedBy uuid.NullUUID `json:"resolved_by"`
+	ResolvedAt *time.Time    `json:"resolved_at"`
+}
+
+
+func newReportResponse(report database.Report) reportResponse {
+	res := reportResponse {
+		ID:         report.ID,
+		CreatedAt:  report.CreatedAt,
+		ChirpID:    report.ChirpID,
+		ReporterID: report.ReporterID,
+		Reason:     report.Reason,
+		Details:    report.Details,
+		Status:     report.Status,
+		ResolvedBy: report.ResolvedBy,
+	}
+	if report.ResolvedAt.Valid {
+		res.ResolvedAt = &report.ResolvedAt.Time
+	}
+	return res
+}
+
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+
+
+
+func (cfg *apiConfig) handlerReportChirp(w http.ResponseWriter, r *http.Request) {
+
+	type request struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	if !reportReasons[req.Reason] {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid report reason"))
+		return
+	}
+
+	if len(req.Details) > 500 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Report details are too long"))
+		return
+	}
+
+	chirp, err := cfg.DBQueries.GetChirp(r.Context(), chirpID)
+	if err != nil || !cfg.canViewChirp(r.Context(), chirp, caller) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Chirp not found"))
+		return
+	}
+
+	if chirp.UserID == caller.UserID {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("You can't report your own chirp"))
+		return
+	}
+
+	report, err := cfg.DBQueries.CreateReport(r.Context(), database.CreateReportParams {
+		ChirpID:    chirpID,
+		ReporterID: caller.UserID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// ON CONFLICT DO NOTHING returns no row
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("You already reported this chirp"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't create report"))
+		return
+	}
+
+	err = cfg.autoHideChirp(r.Context(), chirp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't check report threshold"))
+		return
+	}
+
+	b, err := json.Marshal(newReportResponse(report))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
+
+
+
+// autoHideChirp hides a chirp once its open reports reach the configured
+// threshold. The reports stay open so a moderator still reviews them.
+func (cfg *apiConfig) autoHideChirp(ctx context.Context, chirp database.Chirp) error {
+
+	if cfg.reportHideThreshold <= 0 || chirp.HiddenAt.Valid {
+		return nil
+	}
+
+	count, err := cfg.DBQueries.CountOpenReports(ctx, chirp.ChirpID)
+	if err != nil {
+		return err
+	}
+	if count < int64(cfg.reportHideThreshold) {
+		return nil
+	}
+
+	tx, err := cfg.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	_, err = qtx.HideChirp(ctx, chirp.ChirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// a concurrent report already hid it
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = qtx.CreateModerationAction(ctx, database.CreateModerationActionParams {
+		Action:       actionAutoHide,
+		ChirpID:      uuid.NullUUID{UUID: chirp.ChirpID, Valid: true},
+		TargetUserID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		Reason:       "report threshold reached (" + strconv.FormatInt(count, 10) + " open reports)",
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	if !chirp.PublishAt.Valid {
+		cfg.publishChirpDeleted(chirp)
+	}
+	return nil
+}
+
+
+
+
+func (cfg *apiConfig) handlerListReports(w http.ResponseWriter, r *http.Request) {
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "open"
+	}
+
+	limit, err := parseLimit(r, 50, 200)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	rows, err := cfg.DBQueries.ListReports(r.Context(), database.ListReportsParams {
+		Status: status,
+		Limit:  limit,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list reports"))
+		return
+	}
+
+
+	type response struct {
+		reportResponse
+		ChirpBody     string     `json:"chirp_body"`
+		AuthorID      uuid.UUID  `json:"author_id"`
+		ChirpHiddenAt *time.Time `json:"chirp_hidden_at"`
+	}
+
+	res := []response{}
+
+	for _, row := range rows {
+		res = append(res, response {
+			reportResponse: newReportResponse(database.Report {
+				ID:         row.ID,
+				CreatedAt:  row.CreatedAt,
+				UpdatedAt:  row.UpdatedAt,
+				ChirpID:    row.ChirpID,
+				ReporterID: row.ReporterID,
+				Reason:     row.Reason,
+				Details:    row.Details,
+				Status:     row.Status,
+				ResolvedBy: row.ResolvedBy,
+				ResolvedAt: row.ResolvedAt,
+			}),
+			ChirpBody:     row.ChirpBody,
+			AuthorID:      row.AuthorID,
+			ChirpHiddenAt: nullTimePtr(row.ChirpHiddenAt),
+		})
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerModerateHideChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpHidden(w, r, true)
+}
+
+
+func (cfg *apiConfig) handlerModerateRestoreChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpHidden(w, r, false)
+}
+
+
+// setChirpHidden hides or restores a chirp, resolves its open reports and
+// records the action in the audit trail, all in one transaction.
+func (cfg *apiConfig) setChirpHidden(w http.ResponseWriter, r *http.Request, hide bool) {
+
+	type request struct {
+		Reason string `json:"reason"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	var req request
+
+	// the reason is optional, so an empty body is fine
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+	moderator := uuid.NullUUID{UUID: caller.UserID, Valid: true}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	var chirp database.Chirp
+	action := actionHideChirp
+	reportStatus := "actioned"
+
+	if hide {
+		chirp, err = qtx.HideChirp(r.Context(), chirpID)
+	} else {
+		chirp, err = qtx.UnhideChirp(r.Context(), chirpID)
+		action = actionRestoreChirp
+		reportStatus = "dismissed"
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Chirp not found or already in that state"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't update chirp"))
+		return
+	}
+
+	err = qtx.ResolveReportsForChirp(r.Context(), database.ResolveReportsForChirpParams {
+		ChirpID:    chirpID,
+		Status:     reportStatus,
+		ResolvedBy: moderator,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't resolve reports"))
+		return
+	}
+
+	_, err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams {
+		ModeratorID:  moderator,
+		Action:       action,
+		ChirpID:      uuid.NullUUID{UUID: chirpID, Valid: true},
+		TargetUserID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		Reason:       req.Reason,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't record moderation action"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't commit transaction"))
+		return
+	}
+
+	// live clients drop a hidden chirp like a deleted one
+	if hide && !chirp.PublishAt.Valid {
+		cfg.publishChirpDeleted(chirp)
+	}
+	if !hide {
+		cfg.publishChirpRestored(chirp)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+
+func (cfg *apiConfig) handlerDismissReport(w http.ResponseWriter, r *http.Request) {
+
+	type request struct {
+		Reason string `json:"reason"`
+	}
+
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid report ID"))
+		return
+	}
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+	moderator := uuid.NullUUID{UUID: caller.UserID, Valid: true}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	report, err := qtx.ResolveReport(r.Context(), database.ResolveReportParams {
+		ID:         reportID,
+		Status:     "dismissed",
+		ResolvedBy: moderator,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("No open report with that ID"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't dismiss report"))
+		return
+	}
+
+	_, err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams {
+		ModeratorID: moderator,
+		Action:      actionDismissReport,
+		ChirpID:     uuid.NullUUID{UUID: report.ChirpID, Valid: true},
+		ReportID:    uuid.NullUUID{UUID: report.ID, Valid: true},
+		Reason:      req.Reason,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't record moderation action"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't commit transaction"))
+		return
+	}
+
+	b, err := json.Marshal(newReportResponse(report))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerListModerationActions(w http.ResponseWriter, r *http.Request) {
+
+	limit, err := parseLimit(r, 100, 500)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	actions, err := cfg.DBQueries.ListModerationActions(r.Context(), limit)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list moderation actions"))
+		return
+	}
+
+	if actions == nil {
+		actions = []database.ModerationAction{}
+	}
+
+	b, err := json.Marshal(actions)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+// moderationChirpResponse shows moderators the state regular users can't see,
+// including chirps that were deleted by their author.
+type moderationChirpResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	UserID    uuid.UUID  `json:"user_id"`
+	HiddenAt  *time.Time `json:"hidden_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+
+func newModerationChirpResponse(chirp database.Chirp) moderationChirpResponse {
+	return moderationChirpResponse {
+		ID:        chirp.ChirpID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+		HiddenAt:  nullTimePtr(chirp.HiddenAt),
+		DeletedAt: nullTimePtr(chirp.DeletedAt),
+	}
+}
+
+
+
+
+func (cfg *apiConfig) handlerModerationGetChirp(w http.ResponseWriter, r *http.Request) {
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	chirp, err := cfg.DBQueries.GetChirpIncludingDeleted(r.Context(), chirpID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Chirp not found"))
+		return
+	}
+
+	b, err := json.Marshal(newModerationChirpResponse(chirp))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerModerationListUserChirps(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid user ID"))
+		return
+	}
+
+	chirps, err := cfg.DBQueries.ListChirpsByUserIncludingDeleted(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list chirps"))
+		return
+	}
+
+	res := []moderationChirpResponse{}
+	for _, chirp := range chirps {
+		res = append(res, newModerationChirpResponse(chirp))
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/DylanCoon99/bootdev-server/internal/events"
+	"github.com/google/uuid"
+)
+
+
+
+const (
+	notifyFollow    = "follow"
+	notifyReply     = "reply"
+	notifyLike      = "like"
+	notifyMention   = "mention"
+	notifyChirpyRed = "chirpy_red"
+)
+
+
+// notificationTypes are the types users can mute. Replies come from quotes,
+// follows and likes have no features creating them yet, they are listed so
+// clients can offer the settings up front.
+var notificationTypes = []string{notifyFollow, notifyReply, notifyLike, notifyMention, notifyChirpyRed}
+
+
+func validNotificationType(t string) bool {
+	for _, known := range notificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+
+// mentionPattern finds @handle mentions, the handle rules are the same as
+// in validateHandle.
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@])@([A-Za-z0-9_]{3,15})\b`)
+
+
+func findMentions(body string) []string {
+
+	handles := []string{}
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		handle := strings.ToLower(m[1])
+		if !seen[handle] {
+			seen[handle] = true
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+
+
+type notificationResponse struct {
+	ID        uuid.UUID    `json:"id"`
+	CreatedAt time.Time    `json:"created_at"`
+	Type      string       `json:"type"`
+	Actor     *chirpAuthor `json:"actor"`
+	ChirpID   *uuid.UUID   `json:"chirp_id"`
+	Detail    string       `json:"detail"`
+	ReadAt    *time.Time   `json:"read_at"`
+}
+
+
+func (cfg *apiConfig) notificationResponses(ctx context.Context, notifications []database.Notification) ([]notificationResponse, error) {
+
+	res := make([]notificationResponse, 0, len(notifications))
+
+	actorIDs := []uuid.UUID{}
+	for _, n := range notifications {
+		if n.ActorID.Valid {
+			actorIDs = append(actorIDs, n.ActorID.UUID)
+		}
+	}
+
+	actors := map[uuid.UUID]*chirpAuthor{}
+	if len(actorIDs) > 0 {
+		users, err := cfg.DBQueries.ListUsersByIDs(ctx, actorIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			actors[user.ID] = newChirpAuthor(user)
+		}
+	}
+
+	for _, n := range notifications {
+		var chirpID *uuid.UUID
+		if n.ChirpID.Valid {
+			chirpID = &n.ChirpID.UUID
+		}
+		res = append(res, notificationResponse {
+			ID:        n.ID,
+			CreatedAt: n.CreatedAt,
+			Type:      n.Type,
+			Actor:     actors[n.ActorID.UUID],
+			ChirpID:   chirpID,
+			Detail:    n.Detail,
+			ReadAt:    nullTimePtr(n.ReadAt),
+		})
+	}
+
+	return res, nil
+}
+
+
+
+// notify stores a notification unless the recipient muted its type, and
+// pushes it to their live connections. Failures are logged, whatever
+// caused the notification has already happened.
+func (cfg *apiConfig) notify(ctx context.Context, params database.CreateNotificationParams) {
+
+	// nobody needs to hear about their own actions
+	if params.ActorID.Valid && params.ActorID.UUID == params.UserID {
+		return
+	}
+
+	n, err := cfg.DBQueries.CreateNotification(ctx, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		// muted
+		return
+	}
+	if err != nil {
+		log.Printf("create %s notification for %s: %v", params.Type, params.UserID, err)
+		return
+	}
+
+	res, err := cfg.notificationResponses(ctx, []database.Notification{n})
+	if err != nil {
+		log.Printf("render notification %s: %v", n.ID, err)
+		return
+	}
+
+	data, err := json.Marshal(res[0])
+	if err != nil {
+		log.Printf("encode notification %s: %v", n.ID, err)
+		return
+	}
+
+	err = cfg.events.Publish(events.Event {
+		Type:        events.Notification,
+		RecipientID: n.UserID,
+		Data:        data,
+	})
+	if err != nil {
+		log.Printf("publish notification %s: %v", n.ID, err)
+	}
+}
+
+
+// notifyQuote tells the author of a quoted chirp about the quote. Quotes are
+// the only replies Chirpy has.
+func (cfg *apiConfig) notifyQuote(ctx context.Context, chirp database.Chirp) {
+
+	if !chirp.QuoteOf.Valid {
+		return
+	}
+
+	authorID, err := cfg.DBQueries.GetChirpAuthor(ctx, chirp.QuoteOf.UUID)
+	if err != nil {
+		log.Printf("load author of quoted chirp %s: %v", chirp.QuoteOf.UUID, err)
+		return
+	}
+
+	cfg.notify(ctx, database.CreateNotificationParams {
+		UserID:  authorID,
+		Type:    notifyReply,
+		ActorID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		ChirpID: uuid.NullUUID{UUID: chirp.ChirpID, Valid: true},
+	})
+}
+
+
+// notifyMentions tells everyone @mentioned in a newly published chirp.
+func (cfg *apiConfig) notifyMentions(ctx context.Context, chirp database.Chirp) {
+
+	for _, handle := range findMentions(chirp.Body) {
+		user, err := cfg.DBQueries.GetUserByHandle(ctx, handle)
+		if err != nil {
+			continue
+		}
+		cfg.notify(ctx, database.CreateNotificationParams {
+			UserID:  user.ID,
+			Type:    notifyMention,
+			ActorID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+			ChirpID: uuid.NullUUID{UUID: chirp.ChirpID, Valid: true},
+		})
+	}
+}
+
+
+
+
+func (cfg *apiConfig) handlerListNotifications(w http.ResponseWriter, r *http.Request) {
+
+	// ?unread=true lists only unread ones, ?before=<created_at of the last
+	// notification seen> fetches the next page
+
+	caller, _ := principalFromContext(r.Context())
+
+	limit, err := parseLimit(r, 20, 100)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	params := database.ListNotificationsParams {
+		UserID:     caller.UserID,
+		UnreadOnly: r.URL.Query().Get("unread") == "true",
+		MaxResults: limit,
+	}
+
+	if s := r.URL.Query().Get("before"); s != "" {
+		before, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("before must be an RFC3339 timestamp"))
+			return
+		}
+		params.Before = sql.NullTime{Time: before, Valid: true}
+	}
+
+	notifications, err := cfg.DBQueries.ListNotifications(r.Context(), params)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list notifications"))
+		return
+	}
+
+	res, err := cfg.notificationResponses(r.Context(), notifications)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't load notification actors"))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode notifications to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+func (cfg *apiConfig) handlerUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+
+	type response struct {
+		Count int64 `json:"count"`
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	count, err := cfg.DBQueries.CountUnreadNotifications(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't count notifications"))
+		return
+	}
+
+	b, err := json.Marshal(response{Count: count})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode count to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+func (cfg *apiConfig) handlerMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+
+	notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid notification ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	_, err = cfg.DBQueries.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams {
+		ID:     notificationID,
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Notification not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+func (cfg *apiConfig) handlerMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	_, err := cfg.DBQueries.MarkAllNotificationsRead(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't mark notifications read"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+
+// writeNotificationPreferences responds with every notification type and
+// whether it is enabled.
+func (cfg *apiConfig) writeNotificationPreferences(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+
+	muted, err := cfg.DBQueries.ListNotificationMutes(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't load notification preferences"))
+		return
+	}
+
+	prefs := map[string]bool{}
+	for _, t := range notificationTypes {
+		prefs[t] = true
+	}
+	for _, t := range muted {
+		prefs[t] = false
+	}
+
+	b, err := json.Marshal(prefs)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode preferences to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+func (cfg *apiConfig) handlerGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	caller, _ := principalFromContext(r.Context())
+	cfg.writeNotificationPreferences(w, r, caller.UserID)
+}
+
+
+
+func (cfg *apiConfig) handlerUpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+
+	// takes {"<type>": true|false, ...}, types left out are unchanged
+
+	caller, _ := principalFromContext(r.Context())
+
+	var req map[string]bool
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	for t := range req {
+		if !validNotificationType(t) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Unknown notification type: " + t))
+			return
+		}
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	for t, enabled := range req {
+		if enabled {
+			err = qtx.UnmuteNotificationType(r.Context(), database.UnmuteNotificationTypeParams {
+				UserID: caller.UserID,
+				Type:   t,
+			})
+		} else {
+			err = qtx.MuteNotificationType(r.Context(), database.MuteNotificationTypeParams {
+				UserID: caller.UserID,
+				Type:   t,
+			})
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Couldn't save notification preferences"))
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't save notification preferences"))
+		return
+	}
+
+	cfg.writeNotificationPreferences(w, r, caller.UserID)
+}
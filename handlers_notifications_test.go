@@ -0,0 +1,30 @@
+package main
+
+
+import (
+	"reflect"
+	"testing"
+)
+
+
+
+func TestFindMentions(t *testing.T) {
+
+	cases := []struct {
+		body string
+		want []string
+	}{
+		{"hello @Walt and @jesse_p!", []string{"walt", "jesse_p"}},
+		{"@walt @WALT twice", []string{"walt"}},
+		{"mail me at walt@breakingbad.com", []string{}},
+		{"too short @ab, too long @abcdefghijklmnop", []string{}},
+		{"@@walt isn't a mention", []string{}},
+	}
+
+	for _, c := range cases {
+		got := findMentions(c.body)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("findMentions(%q) = %v, want %v", c.body, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+// Pinned chirps are listed first when getChirpsHandler filters by author.
+// Chirpy Red members get more pins.
+
+
+func (cfg *apiConfig) pinLimit(user database.User) int {
+	if user.IsChirpyRed {
+		return cfg.maxPinsRed
+	}
+	return cfg.maxPins
+}
+
+
+
+
+func (cfg *apiConfig) handlerPinChirp(w http.ResponseWriter, r *http.Request) {
+
+	chirp_id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+	userID := caller.UserID
+
+	user, err := cfg.DBQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(401)
+		w.Write([]byte("User not found"))
+		return
+	}
+
+	// check if the chirp belongs to this user.
+	chirp, err := cfg.DBQueries.GetChirp(r.Context(), chirp_id)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Chirp not found"))
+		return
+	}
+	if chirp.UserID != userID {
+		w.WriteHeader(403)
+		w.Write([]byte("Authentication failed"))
+		return
+	}
+
+	if chirp.RechirpOf.Valid || chirp.PublishAt.Valid {
+		w.WriteHeader(400)
+		w.Write([]byte("Only published chirps of your own can be pinned"))
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Couldn't start transaction"))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DBQueries.WithTx(tx)
+
+	err = qtx.LockUserForPinning(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Couldn't lock pins"))
+		return
+	}
+
+	count, err := qtx.CountPinnedChirps(r.Context(), userID)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Couldn't count pins"))
+		return
+	}
+	if count >= int64(cfg.pinLimit(user)) {
+		w.WriteHeader(409)
+		w.Write([]byte(fmt.Sprintf("You can pin at most %d chirps", cfg.pinLimit(user))))
+		return
+	}
+
+	pin, err := qtx.PinChirp(r.Context(), database.PinChirpParams {
+		ChirpID: chirp_id,
+		UserID:  userID,
+	})
+	if isUniqueViolation(err) {
+		w.WriteHeader(409)
+		w.Write([]byte("Chirp is already pinned"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Couldn't pin chirp"))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Couldn't commit transaction"))
+		return
+	}
+
+	b, err := json.Marshal(pin)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte("Failed to encode pin to json"))
+		return
+	}
+
+	w.WriteHeader(201)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerUnpinChirp(w http.ResponseWriter, r *http.Request) {
+
+	chirp_id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+	userID := caller.UserID
+
+	// check if the chirp belongs to this user.
+	chirp, err := cfg.DBQueries.GetChirp(r.Context(), chirp_id)
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Chirp not found"))
+		return
+	}
+	if chirp.UserID != userID {
+		w.WriteHeader(403)
+		w.Write([]byte("Authentication failed"))
+		return
+	}
+
+	_, err = cfg.DBQueries.UnpinChirp(r.Context(), database.UnpinChirpParams {
+		ChirpID: chirp_id,
+		UserID:  userID,
+	})
+	if err != nil {
+		w.WriteHeader(404)
+		w.Write([]byte("Chirp is not pinned"))
+		return
+	}
+
+	w.WriteHeader(204)
+}
@@ -0,0 +1,43 @@
+package main
+
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+)
+
+
+
+func TestPinnedChirpsListedFirstForAuthor(t *testing.T) {
+
+	// the fake database has one pinned chirp and nothing else
+	cfg, _ := newFakeConfig(t, map[string]interface{}{
+		"ListPinnedChirps": database.Chirp{},
+	})
+
+	get := func(url string) []chirpResponse {
+		w := httptest.NewRecorder()
+		cfg.getChirpsHandler(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != 200 {
+			t.Fatalf("%s: unexpected status %d: %s", url, w.Code, w.Body.String())
+		}
+		var res []chirpResponse
+		err := json.Unmarshal(w.Body.Bytes(), &res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := get("/api/chirps?author_id=" + fakeUserID.String() + "&sort=desc")
+	if len(res) != 1 || !res[0].Pinned {
+		t.Fatalf("want the pinned chirp flagged first, got %+v", res)
+	}
+
+	res = get("/api/chirps")
+	if len(res) != 0 {
+		t.Fatalf("pins are only listed under author_id, got %+v", res)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 50
+	maxPollDuration     = 7 * 24 * time.Hour
+)
+
+
+type pollRequest struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+
+type pollOptionResponse struct {
+	ID   uuid.UUID `json:"id"`
+	Text string    `json:"text"`
+	// null until the viewer has voted or the poll has closed
+	Votes *int64 `json:"votes"`
+}
+
+
+type pollResponse struct {
+	ClosesAt   time.Time            `json:"closes_at"`
+	Closed     bool                 `json:"closed"`
+	Options    []pollOptionResponse `json:"options"`
+	TotalVotes *int64               `json:"total_votes"`
+	VotedFor   *uuid.UUID           `json:"voted_for"`
+}
+
+
+
+// validatePoll checks a poll against the chirp it comes with, starting is
+// when the chirp goes out. It returns the cleaned option texts, or a message
+// for the client.
+func validatePoll(req pollRequest, starting time.Time) ([]string, string) {
+
+	if len(req.Options) < minPollOptions || len(req.Options) > maxPollOptions {
+		return nil, fmt.Sprintf("A poll needs %d to %d options", minPollOptions, maxPollOptions)
+	}
+
+	options := make([]string, 0, len(req.Options))
+	seen := map[string]bool{}
+	for _, option := range req.Options {
+		option = strings.TrimSpace(option)
+		status, cleaned := validateText(option, maxPollOptionLength)
+		if option == "" || status != 200 {
+			return nil, fmt.Sprintf("Poll options must be 1 to %d characters", maxPollOptionLength)
+		}
+		if seen[strings.ToLower(cleaned)] {
+			return nil, "Poll options must be different"
+		}
+		seen[strings.ToLower(cleaned)] = true
+		options = append(options, cleaned)
+	}
+
+	if !req.ClosesAt.After(starting) {
+		return nil, "closes_at must be after the chirp is published"
+	}
+	if req.ClosesAt.Sub(starting) > maxPollDuration {
+		return nil, "A poll can run for at most 7 days"
+	}
+
+	return options, ""
+}
+
+
+
+// createPoll stores a validated poll, inside the transaction creating the
+// chirp.
+func createPoll(ctx context.Context, q *database.Queries, chirpID uuid.UUID, closesAt time.Time, options []string) error {
+
+	_, err := q.CreatePoll(ctx, database.CreatePollParams {
+		ChirpID:  chirpID,
+		ClosesAt: closesAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, text := range options {
+		_, err = q.CreatePollOption(ctx, database.CreatePollOptionParams {
+			ChirpID:  chirpID,
+			Position: int32(i),
+			Text:     text,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+
+
+// chirpPolls loads the polls of the given chirps as the viewer in ctx gets
+// to see them. Tallies are left out until the viewer has voted, or for
+// everyone until the poll closes.
+func (cfg *apiConfig) chirpPolls(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID]*pollResponse, error) {
+
+	res := map[uuid.UUID]*pollResponse{}
+
+	polls, err := cfg.DBQueries.ListPollsByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(polls) == 0 {
+		return res, nil
+	}
+
+	pollIDs := make([]uuid.UUID, 0, len(polls))
+	for _, p := range polls {
+		pollIDs = append(pollIDs, p.ChirpID)
+	}
+
+	options, err := cfg.DBQueries.ListPollOptionsByChirpIDs(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := cfg.DBQueries.CountPollVotes(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	votes := map[uuid.UUID]int64{}
+	for _, c := range counts {
+		votes[c.OptionID] = c.Votes
+	}
+
+	voted := map[uuid.UUID]uuid.UUID{}
+	if caller, ok := principalFromContext(ctx); ok {
+		own, err := cfg.DBQueries.ListPollVotesByUser(ctx, database.ListPollVotesByUserParams {
+			UserID:   caller.UserID,
+			ChirpIds: pollIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range own {
+			voted[v.ChirpID] = v.OptionID
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, p := range polls {
+		poll := &pollResponse {
+			ClosesAt: p.ClosesAt,
+			Closed:   !p.ClosesAt.After(now),
+			Options:  []pollOptionResponse{},
+		}
+		if optionID, ok := voted[p.ChirpID]; ok {
+			poll.VotedFor = &optionID
+		}
+		res[p.ChirpID] = poll
+	}
+
+	for _, o := range options {
+		poll := res[o.ChirpID]
+		option := pollOptionResponse {
+			ID:   o.ID,
+			Text: o.Text,
+		}
+		if poll.Closed || poll.VotedFor != nil {
+			count := votes[o.ID]
+			option.Votes = &count
+			if poll.TotalVotes == nil {
+				poll.TotalVotes = new(int64)
+			}
+			*poll.TotalVotes += count
+		}
+		poll.Options = append(poll.Options, option)
+	}
+
+	return res, nil
+}
+
+
+
+
+func (cfg *apiConfig) handlerVote(w http.ResponseWriter, r *http.Request) {
+
+	// voting again before the poll closes changes the vote
+
+	type request struct {
+		OptionID uuid.UUID `json:"option_id"`
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	user, err := cfg.DBQueries.GetUserByID(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("User not found"))
+		return
+	}
+	if restriction := accountRestriction(user); restriction != "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(restriction))
+		return
+	}
+
+	chirp, err := cfg.DBQueries.GetChirp(r.Context(), chirpID)
+	if err != nil || !cfg.canViewChirp(r.Context(), chirp, caller) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Chirp not found"))
+		return
+	}
+
+	poll, err := cfg.DBQueries.GetPoll(r.Context(), chirpID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Chirp has no poll"))
+		return
+	}
+
+	if !poll.ClosesAt.After(time.Now().UTC()) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Poll is closed"))
+		return
+	}
+
+	blocked, err := cfg.DBQueries.IsBlocked(r.Context(), database.IsBlockedParams {
+		BlockerID: chirp.UserID,
+		BlockedID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't check blocks"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("You can't vote on this poll"))
+		return
+	}
+
+	options, err := cfg.DBQueries.ListPollOptionsByChirpIDs(r.Context(), []uuid.UUID{chirpID})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't load poll"))
+		return
+	}
+
+	valid := false
+	for _, o := range options {
+		if o.ID == req.OptionID {
+			valid = true
+		}
+	}
+	if !valid {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid option"))
+		return
+	}
+
+	// the database has the final say on the closing time
+	_, err = cfg.DBQueries.CastVote(r.Context(), database.CastVoteParams {
+		UserID:   caller.UserID,
+		OptionID: req.OptionID,
+		ChirpID:  chirpID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Poll is closed"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't cast vote"))
+		return
+	}
+
+	polls, err := cfg.chirpPolls(r.Context(), []uuid.UUID{chirpID})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't load poll"))
+		return
+	}
+
+	b, err := json.Marshal(polls[chirpID])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode poll to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
@@ -0,0 +1,178 @@
+package main
+
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+func TestValidatePoll(t *testing.T) {
+
+	now := time.Now()
+
+	cases := []struct {
+		name    string
+		req     pollRequest
+		want    []string
+		wantErr bool
+	}{
+		{"ok", pollRequest{Options: []string{" yes ", "no"}, ClosesAt: now.Add(time.Hour)}, []string{"yes", "no"}, false},
+		{"filtered", pollRequest{Options: []string{"kerfuffle", "fine"}, ClosesAt: now.Add(time.Hour)}, []string{"****", "fine"}, false},
+		{"one option", pollRequest{Options: []string{"yes"}, ClosesAt: now.Add(time.Hour)}, nil, true},
+		{"five options", pollRequest{Options: []string{"a", "b", "c", "d", "e"}, ClosesAt: now.Add(time.Hour)}, nil, true},
+		{"empty option", pollRequest{Options: []string{"a", "  "}, ClosesAt: now.Add(time.Hour)}, nil, true},
+		{"duplicates", pollRequest{Options: []string{"Yes", "yes"}, ClosesAt: now.Add(time.Hour)}, nil, true},
+		{"already closed", pollRequest{Options: []string{"a", "b"}, ClosesAt: now.Add(-time.Minute)}, nil, true},
+		{"too long", pollRequest{Options: []string{"a", "b"}, ClosesAt: now.Add(maxPollDuration + time.Hour)}, nil, true},
+	}
+
+	for _, c := range cases {
+		got, msg := validatePoll(c.req, now)
+		if (msg != "") != c.wantErr {
+			t.Errorf("%s: got error %q, want error %v", c.name, msg, c.wantErr)
+			continue
+		}
+		if !c.wantErr && !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got options %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+
+
+// voteRows is a poll on a chirp by fakeUserID with two options, open for
+// another hour.
+func voteRows(optionA, optionB uuid.UUID) map[string]interface{} {
+	return map[string]interface{}{
+		"GetChirp":                  database.Chirp{},
+		"GetPoll":                   database.Poll{ClosesAt: time.Now().Add(time.Hour)},
+		"IsBlocked":                 struct{ Exists bool }{},
+		"ListPollsByChirpIDs":       database.Poll{ClosesAt: time.Now().Add(time.Hour)},
+		"ListPollOptionsByChirpIDs": []database.PollOption{{ID: optionA, Text: "a"}, {ID: optionB, Text: "b"}},
+		"ListPollVotesByUser":       database.PollVote{OptionID: optionA},
+	}
+}
+
+
+func vote(cfg *apiConfig, optionID uuid.UUID) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/api/chirps/"+fakeUserID.String()+"/vote", strings.NewReader(`{"option_id":"`+optionID.String()+`"}`))
+	r.SetPathValue("chirpID", fakeUserID.String())
+	w := httptest.NewRecorder()
+	cfg.handlerVote(w, asCaller(r, fakeUserID, auth.RoleUser))
+	return w
+}
+
+
+
+func TestVoteOnClosedPoll(t *testing.T) {
+
+	// the poll still looks open to the handler, but CastVote finds it
+	// closed, which the fake database does by not listing it
+	optionA, optionB := uuid.New(), uuid.New()
+	cfg, _ := newFakeConfig(t, voteRows(optionA, optionB))
+
+	takeFakeCalls()
+	w := vote(cfg, optionA)
+	if w.Code != 403 || w.Body.String() != "Poll is closed" {
+		t.Fatalf("want 403 Poll is closed, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := callNames(takeFakeCalls()); !strings.HasSuffix(got, "CastVote") {
+		t.Fatalf("want the vote turned down by CastVote, got %s", got)
+	}
+}
+
+
+
+func TestChangeVote(t *testing.T) {
+
+	optionA, optionB := uuid.New(), uuid.New()
+	rows := voteRows(optionA, optionB)
+	rows["CastVote"] = database.PollVote{}
+	cfg, _ := newFakeConfig(t, rows)
+
+	for _, optionID := range []uuid.UUID{optionA, optionB} {
+		takeFakeCalls()
+		w := vote(cfg, optionID)
+		if w.Code != 200 {
+			t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+		}
+
+		// a changed vote is the same single upsert as the first one
+		casts := 0
+		for _, c := range takeFakeCalls() {
+			if c.name == "CastVote" {
+				casts++
+				if !argsContain(c.args, optionID) {
+					t.Fatalf("CastVote without option %s: %v", optionID, c.args)
+				}
+			}
+		}
+		if casts != 1 {
+			t.Fatalf("want one CastVote, got %d", casts)
+		}
+	}
+
+	if w := vote(cfg, uuid.New()); w.Code != 400 {
+		t.Fatalf("unknown option: want 400, got %d", w.Code)
+	}
+}
+
+
+
+func TestChirpPollsHidesTallies(t *testing.T) {
+
+	optionA, optionB := uuid.New(), uuid.New()
+	rows := voteRows(optionA, optionB)
+	rows["CountPollVotes"] = []database.CountPollVotesRow{{OptionID: optionA, Votes: 3}, {OptionID: optionB, Votes: 2}}
+
+	viewer := asCaller(httptest.NewRequest("GET", "/", nil), uuid.New(), auth.RoleUser).Context()
+
+	get := func(rows map[string]interface{}, ctx context.Context) *pollResponse {
+		cfg, _ := newFakeConfig(t, rows)
+		polls, err := cfg.chirpPolls(ctx, []uuid.UUID{fakeUserID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return polls[fakeUserID]
+	}
+
+	// anonymous, and a viewer who hasn't voted
+	withoutVote := map[string]interface{}{}
+	for name, model := range rows {
+		withoutVote[name] = model
+	}
+	delete(withoutVote, "ListPollVotesByUser")
+
+	for _, ctx := range []context.Context{context.Background(), viewer} {
+		poll := get(withoutVote, ctx)
+		if poll.TotalVotes != nil || poll.Options[0].Votes != nil || poll.VotedFor != nil {
+			b, _ := json.Marshal(poll)
+			t.Fatalf("want tallies hidden before voting, got %s", b)
+		}
+	}
+
+	// the viewer voted
+	poll := get(rows, viewer)
+	if poll.VotedFor == nil || *poll.VotedFor != optionA || poll.TotalVotes == nil || *poll.TotalVotes != 5 {
+		b, _ := json.Marshal(poll)
+		t.Fatalf("want tallies after voting, got %s", b)
+	}
+
+	// the poll closed, everyone sees the result
+	withoutVote["ListPollsByChirpIDs"] = database.Poll{ClosesAt: time.Now().Add(-time.Hour)}
+	poll = get(withoutVote, context.Background())
+	if !poll.Closed || poll.TotalVotes == nil || *poll.TotalVotes != 5 || *poll.Options[1].Votes != 2 {
+		b, _ := json.Marshal(poll)
+		t.Fatalf("want tallies once closed, got %s", b)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/lib/pq"
+)
+
+
+
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,15}$`)
+
+
+// reservedHandles can't be claimed by anyone, since they would be confusing
+// or clash with our own routes. Compared case-insensitively.
+var reservedHandles = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"app":           true,
+	"chirpy":        true,
+	"help":          true,
+	"login":         true,
+	"logout":        true,
+	"me":            true,
+	"mod":           true,
+	"moderator":     true,
+	"null":          true,
+	"root":          true,
+	"settings":      true,
+	"signup":        true,
+	"staff":         true,
+	"support":       true,
+	"system":        true,
+	"undefined":     true,
+}
+
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 500
+)
+
+
+
+// validateHandle returns a message explaining what is wrong with the handle,
+// or an empty string if it can be used.
+func validateHandle(handle string) string {
+
+	if !handlePattern.MatchString(handle) {
+		return "Handle must be 3 to 15 letters, numbers or underscores"
+	}
+
+	if reservedHandles[strings.ToLower(handle)] {
+		return "That handle is reserved"
+	}
+
+	return ""
+}
+
+
+func validateAvatarURL(s string) string {
+
+	if s == "" {
+		return ""
+	}
+
+	if len(s) > maxAvatarURLLength {
+		return "Avatar URL is too long"
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "Avatar URL must be an http or https URL"
+	}
+
+	return ""
+}
+
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+
+
+
+func (cfg *apiConfig) handlerGetProfile(w http.ResponseWriter, r *http.Request) {
+
+	user, err := cfg.DBQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil || user.BannedAt.Valid || user.DeletionRequestedAt.Valid {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("User not found"))
+		return
+	}
+
+	b, err := json.Marshal(newPublicUserResponse(user))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+
+func (cfg *apiConfig) handlerUpdateProfile(w http.ResponseWriter, r *http.Request) {
+
+	// replaces the whole public profile, an empty handle removes it
+
+	type request struct {
+		Handle      string `json:"handle"`
+		DisplayName string `json:"display_name"`
+		Bio         string `json:"bio"`
+		AvatarURL   string `json:"avatar_url"`
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	handle := sql.NullString{}
+	if req.Handle != "" {
+		if msg := validateHandle(req.Handle); msg != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(msg))
+			return
+		}
+		handle = sql.NullString{String: req.Handle, Valid: true}
+	}
+
+	if utf8.RuneCountInString(req.DisplayName) > maxDisplayNameLength {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Display name is too long"))
+		return
+	}
+
+	if utf8.RuneCountInString(req.Bio) > maxBioLength {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Bio is too long"))
+		return
+	}
+
+	if msg := validateAvatarURL(req.AvatarURL); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(msg))
+		return
+	}
+
+	user, err := cfg.DBQueries.UpdateUserProfile(r.Context(), database.UpdateUserProfileParams {
+		ID:          caller.UserID,
+		Handle:      handle,
+		DisplayName: strings.TrimSpace(req.DisplayName),
+		Bio:         strings.TrimSpace(req.Bio),
+		AvatarUrl:   req.AvatarURL,
+	})
+	if isUniqueViolation(err) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("That handle is taken"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't update profile"))
+		return
+	}
+
+	b, err := json.Marshal(newPublicUserResponse(user))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode json response"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+// shareTarget loads the chirp a rechirp or quote should point at. Only
+// chirps everyone can see can be shared, and only by a viewer who hasn't
+// blocked or muted their author. Sharing a rechirp shares the original
+// instead.
+func (cfg *apiConfig) shareTarget(ctx context.Context, viewerID, chirpID uuid.UUID) (database.Chirp, error) {
+
+	for i := 0; i < 2; i++ {
+		chirps, err := cfg.DBQueries.ListEmbeddableChirps(ctx, database.ListEmbeddableChirpsParams {
+			ChirpIds: []uuid.UUID{chirpID},
+			ViewerID: viewerID,
+		})
+		if err != nil {
+			return database.Chirp{}, err
+		}
+		if len(chirps) == 0 {
+			return database.Chirp{}, sql.ErrNoRows
+		}
+		if !chirps[0].RechirpOf.Valid {
+			return chirps[0], nil
+		}
+		chirpID = chirps[0].RechirpOf.UUID
+	}
+
+	// rechirps always point at an original, so this is never reached
+	return database.Chirp{}, sql.ErrNoRows
+}
+
+
+
+func (cfg *apiConfig) handlerRechirp(w http.ResponseWriter, r *http.Request) {
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	user, err := cfg.DBQueries.GetUserByID(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("User not found"))
+		return
+	}
+	if restriction := accountRestriction(user); restriction != "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(restriction))
+		return
+	}
+
+	original, err := cfg.shareTarget(r.Context(), caller.UserID, chirpID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Chirp not found"))
+		return
+	}
+
+	blocked, err := cfg.DBQueries.IsBlocked(r.Context(), database.IsBlockedParams {
+		BlockerID: original.UserID,
+		BlockedID: caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't check blocks"))
+		return
+	}
+	if blocked {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("You can't rechirp this user"))
+		return
+	}
+
+	rechirp, err := cfg.DBQueries.CreateRechirp(r.Context(), database.CreateRechirpParams {
+		UserID:    caller.UserID,
+		RechirpOf: uuid.NullUUID{UUID: original.ChirpID, Valid: true},
+	})
+	if isUniqueViolation(err) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("You already rechirped this chirp"))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't rechirp"))
+		return
+	}
+
+	cfg.chirpPublished(rechirp)
+
+	res, err := cfg.chirpResponseFor(r.Context(), rechirp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load chirp author"))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode chirp to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
+
+
+
+func (cfg *apiConfig) handlerUndoRechirp(w http.ResponseWriter, r *http.Request) {
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	// {chirpID} is the original, the same id that was rechirped
+	rechirp, err := cfg.DBQueries.UndoRechirp(r.Context(), database.UndoRechirpParams {
+		UserID:    caller.UserID,
+		RechirpOf: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("You haven't rechirped this chirp"))
+		return
+	}
+
+	cfg.publishChirpDeleted(rechirp)
+
+	w.WriteHeader(http.StatusNoContent)
+}
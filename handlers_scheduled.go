@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+)
+
+
+
+
+func (cfg *apiConfig) handlerListScheduledChirps(w http.ResponseWriter, r *http.Request) {
+
+	caller, _ := principalFromContext(r.Context())
+
+	chirps, err := cfg.DBQueries.ListScheduledChirps(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't list scheduled chirps"))
+		return
+	}
+
+	res, err := cfg.chirpResponses(r.Context(), chirps)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load chirp authors"))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode chirps to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+func (cfg *apiConfig) handlerRescheduleChirp(w http.ResponseWriter, r *http.Request) {
+
+	type request struct {
+		PublishAt time.Time `json:"publish_at"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	var req request
+
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Failed to decode the request"))
+		return
+	}
+
+	if !req.PublishAt.After(time.Now()) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("publish_at must be in the future"))
+		return
+	}
+
+	// a chirp the scheduler already published no longer matches
+	chirp, err := cfg.DBQueries.RescheduleChirp(r.Context(), database.RescheduleChirpParams {
+		PublishAt: req.PublishAt.UTC(),
+		ChirpID:   chirpID,
+		UserID:    caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Scheduled chirp not found"))
+		return
+	}
+
+	res, err := cfg.chirpResponseFor(r.Context(), chirp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to load chirp author"))
+		return
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to encode chirp to json"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+
+
+func (cfg *apiConfig) handlerCancelScheduledChirp(w http.ResponseWriter, r *http.Request) {
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid chirp ID"))
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+
+	// a chirp that was never published can go for good, there is nothing to
+	// undo
+	_, err = cfg.DBQueries.CancelScheduledChirp(r.Context(), database.CancelScheduledChirpParams {
+		ChirpID: chirpID,
+		UserID:  caller.UserID,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Scheduled chirp not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
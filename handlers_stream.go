@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/DylanCoon99/bootdev-server/internal/events"
+	"github.com/google/uuid"
+)
+
+
+
+// publishChirpCreated tells live clients about a chirp that just went
+// public. The chirp is rendered once here rather than for every client.
+func (cfg *apiConfig) publishChirpCreated(chirp database.Chirp) {
+
+	res, err := cfg.chirpResponseFor(context.Background(), chirp)
+	if err != nil {
+		log.Printf("render chirp %s for live clients: %v", chirp.ChirpID, err)
+		return
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		log.Printf("encode chirp %s for live clients: %v", chirp.ChirpID, err)
+		return
+	}
+
+	err = cfg.events.Publish(events.Event {
+		Type:           events.ChirpCreated,
+		UserID:         chirp.UserID,
+		ThreadID:       threadID(chirp),
+		OriginalUserID: cfg.originalAuthor(chirp),
+		Data:           data,
+	})
+	if err != nil {
+		log.Printf("publish chirp %s: %v", chirp.ChirpID, err)
+	}
+}
+
+
+func (cfg *apiConfig) publishChirpDeleted(chirp database.Chirp) {
+
+	data, _ := json.Marshal(map[string]uuid.UUID{"id": chirp.ChirpID})
+
+	err := cfg.events.Publish(events.Event {
+		Type:           events.ChirpDeleted,
+		UserID:         chirp.UserID,
+		ThreadID:       threadID(chirp),
+		OriginalUserID: cfg.originalAuthor(chirp),
+		Data:           data,
+	})
+	if err != nil {
+		log.Printf("publish deleted chirp %s: %v", chirp.ChirpID, err)
+	}
+}
+
+
+// publishChirpRestored tells live clients about a chirp that is back after
+// being hidden or deleted, as long as timelines list it again.
+func (cfg *apiConfig) publishChirpRestored(chirp database.Chirp) {
+
+	listed, err := cfg.DBQueries.IsChirpListed(context.Background(), chirp.ChirpID)
+	if err != nil {
+		log.Printf("check restored chirp %s: %v", chirp.ChirpID, err)
+		return
+	}
+	if listed {
+		cfg.publishChirpCreated(chirp)
+	}
+}
+
+
+// publishUserChirpsDeleted takes down every chirp of a user whose chirps were
+// just hidden.
+func (cfg *apiConfig) publishUserChirpsDeleted(userID uuid.UUID) {
+
+	chirps, err := cfg.DBQueries.ListLiveChirpsByUser(context.Background(), userID)
+	if err != nil {
+		log.Printf("list chirps of %s for live clients: %v", userID, err)
+		return
+	}
+
+	for _, chirp := range chirps {
+		cfg.publishChirpDeleted(chirp)
+	}
+}
+
+
+// publishUserUpgraded tells live clients a user now has Chirpy Red.
+func (cfg *apiConfig) publishUserUpgraded(user database.User) {
+
+	data, err := json.Marshal(newPublicUserResponse(user))
+	if err != nil {
+		log.Printf("encode user %s for live clients: %v", user.ID, err)
+		return
+	}
+
+	err = cfg.events.Publish(events.Event {
+		Type:   events.UserUpgraded,
+		UserID: user.ID,
+		Data:   data,
+	})
+	if err != nil {
+		log.Printf("publish user upgrade %s: %v", user.ID, err)
+	}
+}
+
+
+// threadID is the chirp a chirp's conversation hangs off.
+func threadID(chirp database.Chirp) uuid.UUID {
+	if chirp.RechirpOf.Valid {
+		return chirp.RechirpOf.UUID
+	}
+	if chirp.QuoteOf.Valid {
+		return chirp.QuoteOf.UUID
+	}
+	return chirp.ChirpID
+}
+
+
+// originalAuthor is who wrote the chirp a rechirp or quote shares, or
+// uuid.Nil for anything else.
+func (cfg *apiConfig) originalAuthor(chirp database.Chirp) uuid.UUID {
+	if !chirp.RechirpOf.Valid && !chirp.QuoteOf.Valid {
+		return uuid.Nil
+	}
+	userID, err := cfg.DBQueries.GetChirpAuthor(context.Background(), threadID(chirp))
+	if err != nil {
+		log.Printf("load original author of chirp %s: %v", chirp.ChirpID, err)
+		return uuid.Nil
+	}
+	return userID
+}
+
+
+
+// writeSSE writes one event in the text/event-stream format.
+func writeSSE(w http.ResponseWriter, ev events.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data)
+	return err
+}
+
+
+
+func (cfg *apiConfig) handlerChirpStream(w http.ResponseWriter, r *http.Request) {
+
+	// GET /api/chirps/stream pushes chirp_created and chirp_deleted events.
+	// A client that reconnects with Last-Event-ID gets what it missed from
+	// the replay buffer, or a "reset" event if it has been away too long and
+	// should reload the timeline instead.
+
+	var authorID uuid.UUID
+	if s := r.URL.Query().Get("author_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Invalid author_id"))
+			return
+		}
+		authorID = id
+	}
+
+	// signed in viewers don't get chirps of people they blocked or muted,
+	// changes apply from the next connection
+	caller, _ := principalFromContext(r.Context())
+	hidden, err := cfg.hiddenAuthors(r.Context(), caller.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't load blocked users"))
+		return
+	}
+
+	wanted := func(ev events.Event) bool {
+		if ev.Type != events.ChirpCreated && ev.Type != events.ChirpDeleted {
+			return false
+		}
+		if hidden[ev.UserID] || hidden[ev.OriginalUserID] {
+			return false
+		}
+		return authorID == uuid.Nil || ev.UserID == authorID
+	}
+
+	rc := http.NewResponseController(w)
+
+	// subscribe before replaying so nothing slips through in between
+	sub := cfg.events.Subscribe(cfg.streamBuffer)
+	defer cfg.events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	var lastSent uint64
+	if s := r.Header.Get("Last-Event-ID"); s != "" {
+		var missed []events.Event
+		lastID, err := strconv.ParseUint(s, 10, 64)
+		ok := err == nil
+		if ok {
+			missed, ok = cfg.events.Since(lastID)
+		}
+		if !ok {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		}
+		for _, ev := range missed {
+			lastSent = ev.ID
+			if wanted(ev) {
+				writeSSE(w, ev)
+			}
+		}
+	}
+
+	err = rc.Flush()
+	if err != nil {
+		// the writer can't stream
+		return
+	}
+
+	heartbeat := time.NewTicker(cfg.streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			// a comment line keeps proxies from closing an idle connection
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+
+		case ev, ok := <-sub.C:
+			if !ok {
+				// we fell too far behind, the client reconnects with
+				// Last-Event-ID and catches up from the replay buffer
+				return
+			}
+			if ev.ID <= lastSent || !wanted(ev) {
+				continue
+			}
+			lastSent = ev.ID
+			err = writeSSE(w, ev)
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
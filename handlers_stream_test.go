@@ -0,0 +1,160 @@
+package main
+
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/events"
+	"github.com/google/uuid"
+)
+
+
+
+// readSSE reads events off a stream until it has n of them, returning their
+// "event: ... / data: ..." lines joined with a space.
+func readSSE(t *testing.T, s *bufio.Scanner, n int) []string {
+
+	got := []string{}
+	current := []string{}
+	for len(got) < n && s.Scan() {
+		line := s.Text()
+		switch {
+		case line == "":
+			if len(current) > 0 {
+				got = append(got, strings.Join(current, " "))
+			}
+			current = nil
+		case strings.HasPrefix(line, "event: "), strings.HasPrefix(line, "data: "):
+			current = append(current, line)
+		}
+	}
+	if len(got) < n {
+		t.Fatalf("stream ended after %d events: %v", len(got), got)
+	}
+	return got
+}
+
+
+func newStreamConfig() *apiConfig {
+	return &apiConfig{
+		events:          events.NewMemory(10),
+		streamBuffer:    8,
+		streamHeartbeat: time.Hour,
+	}
+}
+
+
+
+func TestChirpStream(t *testing.T) {
+
+	cfg := newStreamConfig()
+	author := uuid.New()
+
+	srv := httptest.NewServer(http.HandlerFunc(cfg.handlerChirpStream))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?author_id=" + author.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	cfg.events.Publish(events.Event{Type: events.ChirpCreated, UserID: uuid.New(), Data: []byte(`"someone else"`)})
+	cfg.events.Publish(events.Event{Type: events.ChirpCreated, UserID: author, Data: []byte(`"mine"`)})
+	cfg.events.Publish(events.Event{Type: events.ChirpDeleted, UserID: author, Data: []byte(`"gone"`)})
+
+	got := readSSE(t, bufio.NewScanner(resp.Body), 2)
+	want := []string{
+		`event: chirp_created data: "mine"`,
+		`event: chirp_deleted data: "gone"`,
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("event %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+
+func TestChirpStreamResume(t *testing.T) {
+
+	cfg := newStreamConfig()
+
+	// listen in to learn the IDs the bus hands out
+	probe := cfg.events.Subscribe(3)
+	for _, data := range []string{`1`, `2`, `3`} {
+		cfg.events.Publish(events.Event{Type: events.ChirpCreated, Data: []byte(data)})
+	}
+	first := <-probe.C
+
+	srv := httptest.NewServer(http.HandlerFunc(cfg.handlerChirpStream))
+	defer srv.Close()
+
+	get := func(lastEventID string) *http.Response {
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		req.Header.Set("Last-Event-ID", lastEventID)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := get(strconv.FormatUint(first.ID, 10))
+	got := readSSE(t, bufio.NewScanner(resp.Body), 2)
+	resp.Body.Close()
+	if got[0] != "event: chirp_created data: 2" || got[1] != "event: chirp_created data: 3" {
+		t.Fatalf("unexpected replay %v", got)
+	}
+
+	// an ID the server never handed out can't be resumed from
+	resp = get(strconv.FormatUint(first.ID+42, 10))
+	got = readSSE(t, bufio.NewScanner(resp.Body), 1)
+	resp.Body.Close()
+	if got[0] != "event: reset data: {}" {
+		t.Fatalf("expected a reset event, got %v", got)
+	}
+}
+
+
+func TestChirpStreamHidesBlockedAuthors(t *testing.T) {
+
+	// the fake database lists fakeUserID as blocked or muted by the viewer
+	cfg, _ := newFakeConfig(t, map[string]interface{}{
+		"ListHiddenAuthors": struct{ UserID uuid.UUID }{},
+	})
+	cfg.events = events.NewMemory(10)
+	cfg.streamBuffer = 8
+	cfg.streamHeartbeat = time.Hour
+
+	viewer := uuid.New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg.handlerChirpStream(w, asCaller(r, viewer, auth.RoleUser))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	cfg.events.Publish(events.Event{Type: events.ChirpCreated, UserID: fakeUserID, Data: []byte(`"blocked"`)})
+	cfg.events.Publish(events.Event{Type: events.ChirpCreated, UserID: uuid.New(), OriginalUserID: fakeUserID, Data: []byte(`"rechirp of blocked"`)})
+	cfg.events.Publish(events.Event{Type: events.ChirpCreated, UserID: uuid.New(), Data: []byte(`"visible"`)})
+
+	got := readSSE(t, bufio.NewScanner(resp.Body), 1)
+	if got[0] != `event: chirp_created data: "visible"` {
+		t.Fatalf("got %q, want only the visible chirp", got[0])
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/events"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+
+
+// The WebSocket API at /api/ws carries the same events as the SSE stream,
+// plus notifications, over one connection. Clients send
+//
+//	{"type": "subscribe", "topic": "timeline"}
+//	{"type": "subscribe", "topic": "user:<user id>"}
+//	{"type": "subscribe", "topic": "thread:<chirp id>"}
+//	{"type": "subscribe", "topic": "notifications"}
+//
+// (and "unsubscribe" with the same topics), and receive
+//
+//	{"type": "event", "topic": "...", "event": {...}}
+//
+// for every matching event. The connection is closed when the JWT it was
+// opened with expires, the client reconnects with a fresh one.
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	wsMaxMessage = 4096
+
+	// application close codes, 4000 and up are ours to define
+	wsCloseTokenExpired = 4001
+	wsCloseTooSlow      = 4002
+)
+
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+
+
+// wsConnections counts open WebSocket connections per user.
+type wsConnections struct {
+	mu     sync.Mutex
+	counts map[uuid.UUID]int
+}
+
+
+func newWSConnections() *wsConnections {
+	return &wsConnections{counts: map[uuid.UUID]int{}}
+}
+
+
+// acquire takes a connection slot for userID, reporting false if they
+// already have max connections open.
+func (c *wsConnections) acquire(userID uuid.UUID, max int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts[userID] >= max {
+		return false
+	}
+	c.counts[userID]++
+	return true
+}
+
+
+func (c *wsConnections) release(userID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[userID]--
+	if c.counts[userID] <= 0 {
+		delete(c.counts, userID)
+	}
+}
+
+
+
+type wsClientMessage struct {
+	Type  string `json:"type"`
+	Topic string `json:"topic"`
+}
+
+
+type wsServerMessage struct {
+	Type    string        `json:"type"`
+	Topic   string        `json:"topic,omitempty"`
+	Event   *events.Event `json:"event,omitempty"`
+	Message string        `json:"message,omitempty"`
+}
+
+
+
+// wsTopic is a parsed subscription topic.
+type wsTopic struct {
+	kind string
+	id   uuid.UUID
+}
+
+
+func parseWSTopic(s string) (wsTopic, error) {
+
+	switch s {
+	case "timeline", "notifications":
+		return wsTopic{kind: s}, nil
+	}
+
+	kind, id, found := strings.Cut(s, ":")
+	if !found || (kind != "user" && kind != "thread") {
+		return wsTopic{}, errors.New("unknown topic")
+	}
+
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return wsTopic{}, errors.New("invalid id in topic")
+	}
+
+	return wsTopic{kind: kind, id: parsed}, nil
+}
+
+
+// matches reports whether ev belongs on topic t for the connection's user.
+func (t wsTopic) matches(ev events.Event, userID uuid.UUID) bool {
+
+	if ev.Type == events.Notification {
+		return t.kind == "notifications" && ev.RecipientID == userID
+	}
+
+	switch t.kind {
+	case "timeline":
+		return true
+	case "user":
+		return ev.UserID == t.id
+	case "thread":
+		return ev.ThreadID == t.id
+	}
+	return false
+}
+
+
+
+// wsToken reads the JWT from the Authorization header, or from the
+// access_token query parameter for browsers, which can't set headers on a
+// WebSocket handshake.
+func wsToken(r *http.Request) (string, error) {
+	if r.Header.Get("Authorization") != "" {
+		return auth.GetBearerToken(r.Header)
+	}
+	if token := r.URL.Query().Get("access_token"); token != "" {
+		return token, nil
+	}
+	return "", errNoToken
+}
+
+
+
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, r *http.Request) {
+
+	token, err := wsToken(r)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Missing token"))
+		return
+	}
+
+	claims, err := auth.ParseJWT(token, cfg.jwtSecret)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Invalid token"))
+		return
+	}
+
+	user, err := cfg.DBQueries.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("User not found"))
+		return
+	}
+	if restriction := accountRestriction(user); restriction != "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(restriction))
+		return
+	}
+
+	// blocks and mutes made after connecting apply from the next connection
+	hidden, err := cfg.hiddenAuthors(r.Context(), claims.UserID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Couldn't load blocked users"))
+		return
+	}
+
+	if !cfg.wsConnections.acquire(claims.UserID, cfg.wsMaxPerUser) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte("Too many open connections"))
+		return
+	}
+	defer cfg.wsConnections.release(claims.UserID)
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written the error response
+		return
+	}
+	defer conn.Close()
+
+	cfg.serveWebSocket(conn, claims, hidden)
+}
+
+
+
+// serveWebSocket runs a connection until either side closes it. Reads
+// happen on their own goroutine, everything written goes through this one.
+func (cfg *apiConfig) serveWebSocket(conn *websocket.Conn, claims auth.Claims, hidden map[uuid.UUID]bool) {
+
+	sub := cfg.events.Subscribe(cfg.streamBuffer)
+	defer cfg.events.Unsubscribe(sub)
+
+	conn.SetReadLimit(wsMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	// done tells the reader we have stopped listening, readDone tells us the
+	// client has gone
+	incoming := make(chan wsClientMessage)
+	readDone := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(readDone)
+		for {
+			var msg wsClientMessage
+			err := conn.ReadJSON(&msg)
+			if err != nil {
+				return
+			}
+			select {
+			case incoming <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	write := func(msg wsServerMessage) error {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(msg)
+	}
+
+	closeWith := func(code int, text string) {
+		deadline := time.Now().Add(wsWriteWait)
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), deadline)
+	}
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	expiry := time.NewTimer(time.Until(claims.ExpiresAt))
+	defer expiry.Stop()
+
+	topics := map[string]wsTopic{}
+
+	for {
+		var err error
+
+		select {
+		case <-readDone:
+			return
+
+		case <-expiry.C:
+			closeWith(wsCloseTokenExpired, "token expired")
+			return
+
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+
+		case msg := <-incoming:
+			switch msg.Type {
+			case "subscribe":
+				topic, perr := parseWSTopic(msg.Topic)
+				if perr != nil {
+					err = write(wsServerMessage{Type: "error", Topic: msg.Topic, Message: perr.Error()})
+					break
+				}
+				topics[msg.Topic] = topic
+				err = write(wsServerMessage{Type: "subscribed", Topic: msg.Topic})
+			case "unsubscribe":
+				delete(topics, msg.Topic)
+				err = write(wsServerMessage{Type: "unsubscribed", Topic: msg.Topic})
+			default:
+				err = write(wsServerMessage{Type: "error", Message: "unknown message type"})
+			}
+
+		case ev, ok := <-sub.C:
+			if !ok {
+				closeWith(wsCloseTooSlow, "too slow, reconnect")
+				return
+			}
+			if ev.Type != events.Notification && (hidden[ev.UserID] || hidden[ev.OriginalUserID]) {
+				continue
+			}
+			for name, topic := range topics {
+				if topic.matches(ev, claims.UserID) {
+					err = write(wsServerMessage{Type: "event", Topic: name, Event: &ev})
+					if err != nil {
+						break
+					}
+				}
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
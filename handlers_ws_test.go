@@ -0,0 +1,157 @@
+package main
+
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/events"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+
+
+func TestWSTopics(t *testing.T) {
+
+	me := uuid.New()
+	chirp := uuid.New()
+
+	ev := events.Event{Type: events.ChirpCreated, UserID: me, ThreadID: chirp}
+	note := events.Event{Type: events.Notification, RecipientID: me}
+
+	cases := []struct {
+		topic string
+		ev    events.Event
+		want  bool
+	}{
+		{"timeline", ev, true},
+		{"user:" + me.String(), ev, true},
+		{"user:" + uuid.NewString(), ev, false},
+		{"thread:" + chirp.String(), ev, true},
+		{"notifications", ev, false},
+		{"notifications", note, true},
+		{"timeline", note, false},
+	}
+
+	for _, c := range cases {
+		topic, err := parseWSTopic(c.topic)
+		if err != nil {
+			t.Fatalf("%s: %v", c.topic, err)
+		}
+		if got := topic.matches(c.ev, me); got != c.want {
+			t.Errorf("%s matches %s = %v, want %v", c.topic, c.ev.Type, got, c.want)
+		}
+	}
+
+	// someone else's notifications never match
+	topic, _ := parseWSTopic("notifications")
+	if topic.matches(note, uuid.New()) {
+		t.Error("notification delivered to the wrong user")
+	}
+
+	for _, bad := range []string{"", "users", "user:", "user:nope", "dm:" + me.String()} {
+		if _, err := parseWSTopic(bad); err == nil {
+			t.Errorf("%q should not parse", bad)
+		}
+	}
+}
+
+
+
+func newWSTestServer(t *testing.T, maxPerUser int) (*apiConfig, *httptest.Server) {
+
+	cfg, _ := newFakeConfig(t, nil)
+	cfg.events = events.NewMemory(10)
+	cfg.streamBuffer = 8
+	cfg.wsConnections = newWSConnections()
+	cfg.wsMaxPerUser = maxPerUser
+
+	srv := httptest.NewServer(http.HandlerFunc(cfg.handlerWebSocket))
+	t.Cleanup(srv.Close)
+	return cfg, srv
+}
+
+
+func dialWS(t *testing.T, srv *httptest.Server, expiresIn time.Duration) (*websocket.Conn, *http.Response, error) {
+
+	token, err := auth.MakeJWT(fakeUserID, auth.RoleUser, "secret", expiresIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?access_token=" + token
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+
+
+func TestWebSocketSubscribe(t *testing.T) {
+
+	cfg, srv := newWSTestServer(t, 5)
+
+	conn, _, err := dialWS(t, srv, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	topic := "user:" + fakeUserID.String()
+	conn.WriteJSON(wsClientMessage{Type: "subscribe", Topic: topic})
+
+	var msg wsServerMessage
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	err = conn.ReadJSON(&msg)
+	if err != nil || msg.Type != "subscribed" || msg.Topic != topic {
+		t.Fatalf("expected a subscribed ack, got %+v %v", msg, err)
+	}
+
+	cfg.events.Publish(events.Event{Type: events.ChirpCreated, UserID: uuid.New(), Data: []byte(`1`)})
+	cfg.events.Publish(events.Event{Type: events.ChirpCreated, UserID: fakeUserID, Data: []byte(`2`)})
+
+	err = conn.ReadJSON(&msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "event" || msg.Topic != topic || string(msg.Event.Data) != "2" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+
+func TestWebSocketMaxConnections(t *testing.T) {
+
+	_, srv := newWSTestServer(t, 1)
+
+	first, _, err := dialWS(t, srv, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+
+	_, resp, err := dialWS(t, srv, time.Minute)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second connection should be refused with 429, got %v", err)
+	}
+}
+
+
+func TestWebSocketTokenExpiry(t *testing.T) {
+
+	_, srv := newWSTestServer(t, 5)
+
+	conn, _, err := dialWS(t, srv, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, wsCloseTokenExpired) {
+		t.Fatalf("expected close code %d, got %v", wsCloseTokenExpired, err)
+	}
+}
@@ -45,18 +45,54 @@ func CheckPassword(password, hash string) error {
 
 
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
 
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims {
-		Issuer:    "chirpy",
-		IssuedAt:  &jwt.NumericDate {
-			time.Now(),
-		},
-		ExpiresAt: &jwt.NumericDate {
-			time.Now().Add(expiresIn),
+// roleRank orders roles so that a higher role satisfies every lower one.
+var roleRank = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+
+// HasRole reports whether a caller with role have may act as required, e.g.
+// an admin may do anything a moderator can.
+func HasRole(have, required string) bool {
+	return roleRank[have] >= roleRank[required] && roleRank[required] > 0
+}
+
+
+
+// tokenClaims is what gets signed into access tokens.
+type tokenClaims struct {
+	Role string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+
+
+func MakeJWT(userID uuid.UUID, role string, tokenSecret string, expiresIn time.Duration) (string, error) {
+
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims {
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims {
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
 		},
-		Subject:   userID.String(),
 	})
 
 
@@ -68,40 +104,77 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 
 
 
+// Claims are the validated contents of an access token.
+type Claims struct {
+	UserID    uuid.UUID
+	Role      string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+
+
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claimsStruct := jwt.RegisteredClaims{}
+	claims, err := ParseJWT(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.UserID, nil
+}
+
+
+
+// ParseJWT validates an access token and returns all of its claims, not just
+// the user ID.
+func ParseJWT(tokenString, tokenSecret string) (Claims, error) {
+	claimsStruct := tokenClaims{}
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&claimsStruct,
 		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
 	)
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, err
 	}
 
 	userIDString, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, err
 	}
 
 	issuer, err := token.Claims.GetIssuer()
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, err
 	}
 	if issuer != string("chirpy") {
-		return uuid.Nil, errors.New("invalid issuer")
+		return Claims{}, errors.New("invalid issuer")
 	}
 
 	id, err := uuid.Parse(userIDString)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
+		return Claims{}, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	// tokens issued before roles existed carry no role claim
+	role := claimsStruct.Role
+	if role == "" {
+		role = RoleUser
 	}
-	return id, nil
+
+	claims := Claims{UserID: id, Role: role}
+	if claimsStruct.IssuedAt != nil {
+		claims.IssuedAt = claimsStruct.IssuedAt.Time
+	}
+	if claimsStruct.ExpiresAt != nil {
+		claims.ExpiresAt = claimsStruct.ExpiresAt.Time
+	}
+	return claims, nil
 }
 
 
 
 
+
 func GetBearerToken(headers http.Header) (string, error) {
 	authHeader := headers.Get("Authorization")
 	if authHeader == "" {
@@ -4,7 +4,7 @@ package auth
 import (
 	"time"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	//"golang.org/x/crypto/bcrypt"
 	//"github.com/golang-jwt/jwt/v5"
 	"testing"
 	"fmt"
@@ -20,7 +20,7 @@ func TestMakeJWT(t *testing.T) {
 	/*
 		func signature:
 
-			MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error)
+			MakeJWT(userID uuid.UUID, role string, tokenSecret string, expiresIn time.Duration) (string, error)
 
 	*/
 
@@ -30,7 +30,7 @@ func TestMakeJWT(t *testing.T) {
 
 	expiresIn := time.Minute * 2
 
-	token, err := MakeJWT(user_uuid, tokenSecret, expiresIn)
+	token, err := MakeJWT(user_uuid, RoleUser, tokenSecret, expiresIn)
 
 	//fmt.Println(token, err)
 
@@ -56,7 +56,7 @@ func TestValidateJWT(t *testing.T) {
 
 	expiresIn := time.Minute * 2
 
-	tokenString, err := MakeJWT(user_uuid, tokenSecret, expiresIn)
+	tokenString, err := MakeJWT(user_uuid, RoleUser, tokenSecret, expiresIn)
 
 	user_uuid, err = ValidateJWT(tokenString, tokenSecret)
 
@@ -71,6 +71,77 @@ func TestValidateJWT(t *testing.T) {
 }
 
 
+func TestParseJWTExpired(t *testing.T) {
+
+	// an expired token must not validate
+
+	user_uuid, _ := uuid.NewRandom()
+
+	tokenSecret := "eyJmb28iOiJiYXIiLCJuYmYiOjE0NDQ0Nzg0MDB9.u1riaD1rW97opCoAuRCTy4w58Br-Zk-bh7vLiRIsrpU"
+
+	tokenString, err := MakeJWT(user_uuid, RoleUser, tokenSecret, -time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to make token")
+	}
+
+	_, err = ParseJWT(tokenString, tokenSecret)
+	if err == nil {
+		t.Fatalf("Expired token should not validate")
+	}
+}
+
+
+
+func TestParseJWTClaims(t *testing.T) {
+
+	user_uuid, _ := uuid.NewRandom()
+
+	tokenSecret := "eyJmb28iOiJiYXIiLCJuYmYiOjE0NDQ0Nzg0MDB9.u1riaD1rW97opCoAuRCTy4w58Br-Zk-bh7vLiRIsrpU"
+
+	tokenString, _ := MakeJWT(user_uuid, RoleModerator, tokenSecret, time.Hour)
+
+	claims, err := ParseJWT(tokenString, tokenSecret)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+
+	if claims.UserID != user_uuid {
+		t.Fatalf("Wrong user ID in claims")
+	}
+
+	if claims.Role != RoleModerator {
+		t.Fatalf("Wrong role in claims: %s", claims.Role)
+	}
+
+	if claims.ExpiresAt.Before(time.Now().Add(59 * time.Minute)) {
+		t.Fatalf("Wrong expiry in claims")
+	}
+}
+
+
+func TestHasRole(t *testing.T) {
+
+	cases := []struct {
+		have     string
+		required string
+		want     bool
+	}{
+		{RoleAdmin, RoleModerator, true},
+		{RoleModerator, RoleModerator, true},
+		{RoleUser, RoleModerator, false},
+		{RoleModerator, RoleAdmin, false},
+		{"", RoleUser, false},
+		{RoleAdmin, "superuser", false},
+	}
+
+	for _, c := range cases {
+		if HasRole(c.have, c.required) != c.want {
+			t.Fatalf("HasRole(%q, %q) should be %v", c.have, c.required, c.want)
+		}
+	}
+}
+
+
 /*
 func TestGetBearerToken(t *testing.T) {
 
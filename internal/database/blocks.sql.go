@@ -0,0 +1,151 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: blocks.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const blockUser = `-- name: BlockUser :exec
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type BlockUserParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) BlockUser(ctx context.Context, arg BlockUserParams) error {
+	_, err := q.db.ExecContext(ctx, blockUser, arg.BlockerID, arg.BlockedID)
+	return err
+}
+
+const isBlocked = `-- name: IsBlocked :one
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE blocker_id = $1
+    AND blocked_id = $2
+)
+`
+
+type IsBlockedParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) IsBlocked(ctx context.Context, arg IsBlockedParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlocked, arg.BlockerID, arg.BlockedID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isBlockedByAnyHandle = `-- name: IsBlockedByAnyHandle :one
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    JOIN users ON users.id = user_blocks.blocker_id
+    WHERE user_blocks.blocked_id = $1
+    AND LOWER(users.handle) = ANY($2::text[])
+)
+`
+
+type IsBlockedByAnyHandleParams struct {
+	BlockedID uuid.UUID
+	Handles   []string
+}
+
+// handles are lowercase, as findMentions returns them
+func (q *Queries) IsBlockedByAnyHandle(ctx context.Context, arg IsBlockedByAnyHandleParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlockedByAnyHandle, arg.BlockedID, pq.Array(arg.Handles))
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listHiddenAuthors = `-- name: ListHiddenAuthors :many
+SELECT blocked_id AS user_id FROM user_blocks
+WHERE blocker_id = $1
+UNION
+SELECT muted_id AS user_id FROM user_mutes
+WHERE muter_id = $1
+`
+
+// everyone whose chirps the user doesn't want to see
+func (q *Queries) ListHiddenAuthors(ctx context.Context, blockerID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listHiddenAuthors, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var user_id uuid.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const muteUser = `-- name: MuteUser :exec
+INSERT INTO user_mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type MuteUserParams struct {
+	MuterID uuid.UUID
+	MutedID uuid.UUID
+}
+
+func (q *Queries) MuteUser(ctx context.Context, arg MuteUserParams) error {
+	_, err := q.db.ExecContext(ctx, muteUser, arg.MuterID, arg.MutedID)
+	return err
+}
+
+const unblockUser = `-- name: UnblockUser :exec
+DELETE FROM user_blocks
+WHERE blocker_id = $1
+AND blocked_id = $2
+`
+
+type UnblockUserParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) UnblockUser(ctx context.Context, arg UnblockUserParams) error {
+	_, err := q.db.ExecContext(ctx, unblockUser, arg.BlockerID, arg.BlockedID)
+	return err
+}
+
+const unmuteUser = `-- name: UnmuteUser :exec
+DELETE FROM user_mutes
+WHERE muter_id = $1
+AND muted_id = $2
+`
+
+type UnmuteUserParams struct {
+	MuterID uuid.UUID
+	MutedID uuid.UUID
+}
+
+func (q *Queries) UnmuteUser(ctx context.Context, arg UnmuteUserParams) error {
+	_, err := q.db.ExecContext(ctx, unmuteUser, arg.MuterID, arg.MutedID)
+	return err
+}
@@ -0,0 +1,156 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: drafts.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createDraft = `-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, user_id, body)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2
+)
+RETURNING id, created_at, updated_at, user_id, body
+`
+
+type CreateDraftParams struct {
+	UserID uuid.UUID
+	Body   string
+}
+
+func (q *Queries) CreateDraft(ctx context.Context, arg CreateDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, createDraft, arg.UserID, arg.Body)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+	)
+	return i, err
+}
+
+const deleteDraft = `-- name: DeleteDraft :one
+DELETE FROM drafts
+WHERE id = $1
+AND user_id = $2
+RETURNING id, created_at, updated_at, user_id, body
+`
+
+type DeleteDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// also used to take a draft when publishing it
+func (q *Queries) DeleteDraft(ctx context.Context, arg DeleteDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, deleteDraft, arg.ID, arg.UserID)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+	)
+	return i, err
+}
+
+const getDraft = `-- name: GetDraft :one
+SELECT id, created_at, updated_at, user_id, body FROM drafts
+WHERE id = $1
+AND user_id = $2
+`
+
+type GetDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetDraft(ctx context.Context, arg GetDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, getDraft, arg.ID, arg.UserID)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+	)
+	return i, err
+}
+
+const listDrafts = `-- name: ListDrafts :many
+SELECT id, created_at, updated_at, user_id, body FROM drafts
+WHERE user_id = $1
+ORDER BY updated_at DESC
+LIMIT $2
+`
+
+type ListDraftsParams struct {
+	UserID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) ListDrafts(ctx context.Context, arg ListDraftsParams) ([]Draft, error) {
+	rows, err := q.db.QueryContext(ctx, listDrafts, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Draft
+	for rows.Next() {
+		var i Draft
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateDraft = `-- name: UpdateDraft :one
+UPDATE drafts SET body = $3,
+updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+RETURNING id, created_at, updated_at, user_id, body
+`
+
+type UpdateDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Body   string
+}
+
+func (q *Queries) UpdateDraft(ctx context.Context, arg UpdateDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, updateDraft, arg.ID, arg.UserID, arg.Body)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+	)
+	return i, err
+}
@@ -0,0 +1,439 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: export.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const listBlocksForExport = `-- name: ListBlocksForExport :many
+SELECT blocked_id AS user_id, created_at FROM user_blocks
+WHERE blocker_id = $1
+ORDER BY created_at ASC
+`
+
+type ListBlocksForExportRow struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) ListBlocksForExport(ctx context.Context, blockerID uuid.UUID) ([]ListBlocksForExportRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBlocksForExport, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBlocksForExportRow
+	for rows.Next() {
+		var i ListBlocksForExportRow
+		if err := rows.Scan(&i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBookmarkCollectionsForExport = `-- name: ListBookmarkCollectionsForExport :many
+SELECT id, created_at, updated_at, user_id, name FROM bookmark_collections
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListBookmarkCollectionsForExport(ctx context.Context, userID uuid.UUID) ([]BookmarkCollection, error) {
+	rows, err := q.db.QueryContext(ctx, listBookmarkCollectionsForExport, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BookmarkCollection
+	for rows.Next() {
+		var i BookmarkCollection
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBookmarksForExport = `-- name: ListBookmarksForExport :many
+SELECT bookmarks.chirp_id, bookmarks.created_at, bookmark_collections.name AS collection
+FROM bookmarks
+LEFT JOIN bookmark_collections ON bookmark_collections.id = bookmarks.collection_id
+WHERE bookmarks.user_id = $1
+ORDER BY bookmarks.created_at ASC
+`
+
+type ListBookmarksForExportRow struct {
+	ChirpID    uuid.UUID
+	CreatedAt  time.Time
+	Collection sql.NullString
+}
+
+func (q *Queries) ListBookmarksForExport(ctx context.Context, userID uuid.UUID) ([]ListBookmarksForExportRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBookmarksForExport, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBookmarksForExportRow
+	for rows.Next() {
+		var i ListBookmarksForExportRow
+		if err := rows.Scan(&i.ChirpID, &i.CreatedAt, &i.Collection); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationsForExport = `-- name: ListConversationsForExport :many
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.created_by, conversations.is_group FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversation_participants.user_id = $1
+ORDER BY conversations.created_at ASC
+`
+
+func (q *Queries) ListConversationsForExport(ctx context.Context, userID uuid.UUID) ([]Conversation, error) {
+	rows, err := q.db.QueryContext(ctx, listConversationsForExport, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Conversation
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CreatedBy,
+			&i.IsGroup,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDraftsForExport = `-- name: ListDraftsForExport :many
+
+
+SELECT id, created_at, updated_at, user_id, body FROM drafts
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+// Everything below feeds buildExportArchive, each query returns all of a
+// user's rows of one kind.
+func (q *Queries) ListDraftsForExport(ctx context.Context, userID uuid.UUID) ([]Draft, error) {
+	rows, err := q.db.QueryContext(ctx, listDraftsForExport, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Draft
+	for rows.Next() {
+		var i Draft
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listListMembersForExport = `-- name: ListListMembersForExport :many
+SELECT list_members.list_id, list_members.user_id, list_members.added_at FROM list_members
+JOIN lists ON lists.id = list_members.list_id
+WHERE lists.owner_id = $1
+ORDER BY list_members.added_at ASC
+`
+
+func (q *Queries) ListListMembersForExport(ctx context.Context, ownerID uuid.UUID) ([]ListMember, error) {
+	rows, err := q.db.QueryContext(ctx, listListMembersForExport, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMember
+	for rows.Next() {
+		var i ListMember
+		if err := rows.Scan(&i.ListID, &i.UserID, &i.AddedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listListSubscriptionsForExport = `-- name: ListListSubscriptionsForExport :many
+SELECT list_id, user_id, created_at FROM list_subscriptions
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListListSubscriptionsForExport(ctx context.Context, userID uuid.UUID) ([]ListSubscription, error) {
+	rows, err := q.db.QueryContext(ctx, listListSubscriptionsForExport, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSubscription
+	for rows.Next() {
+		var i ListSubscription
+		if err := rows.Scan(&i.ListID, &i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listListsForExport = `-- name: ListListsForExport :many
+SELECT id, created_at, updated_at, owner_id, name, description, is_private FROM lists
+WHERE owner_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListListsForExport(ctx context.Context, ownerID uuid.UUID) ([]List, error) {
+	rows, err := q.db.QueryContext(ctx, listListsForExport, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []List
+	for rows.Next() {
+		var i List
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.OwnerID,
+			&i.Name,
+			&i.Description,
+			&i.IsPrivate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessageBlocksForExport = `-- name: ListMessageBlocksForExport :many
+SELECT blocked_id AS user_id, created_at FROM message_blocks
+WHERE blocker_id = $1
+ORDER BY created_at ASC
+`
+
+type ListMessageBlocksForExportRow struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) ListMessageBlocksForExport(ctx context.Context, blockerID uuid.UUID) ([]ListMessageBlocksForExportRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMessageBlocksForExport, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMessageBlocksForExportRow
+	for rows.Next() {
+		var i ListMessageBlocksForExportRow
+		if err := rows.Scan(&i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessagesForExport = `-- name: ListMessagesForExport :many
+SELECT messages.id, messages.created_at, messages.conversation_id, messages.sender_id, messages.body FROM messages
+JOIN conversation_participants ON conversation_participants.conversation_id = messages.conversation_id
+WHERE conversation_participants.user_id = $1
+ORDER BY messages.created_at ASC
+`
+
+// the whole of every conversation the user is in, not just what they sent
+func (q *Queries) ListMessagesForExport(ctx context.Context, userID uuid.UUID) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, listMessagesForExport, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMutesForExport = `-- name: ListMutesForExport :many
+SELECT muted_id AS user_id, created_at FROM user_mutes
+WHERE muter_id = $1
+ORDER BY created_at ASC
+`
+
+type ListMutesForExportRow struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) ListMutesForExport(ctx context.Context, muterID uuid.UUID) ([]ListMutesForExportRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMutesForExport, muterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMutesForExportRow
+	for rows.Next() {
+		var i ListMutesForExportRow
+		if err := rows.Scan(&i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPollVotesForExport = `-- name: ListPollVotesForExport :many
+SELECT poll_votes.chirp_id, poll_votes.created_at, poll_votes.updated_at, poll_options.text AS option
+FROM poll_votes
+JOIN poll_options ON poll_options.id = poll_votes.option_id
+WHERE poll_votes.user_id = $1
+ORDER BY poll_votes.created_at ASC
+`
+
+type ListPollVotesForExportRow struct {
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Option    string
+}
+
+func (q *Queries) ListPollVotesForExport(ctx context.Context, userID uuid.UUID) ([]ListPollVotesForExportRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPollVotesForExport, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPollVotesForExportRow
+	for rows.Next() {
+		var i ListPollVotesForExportRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Option,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,114 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: link_previews.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+const getLinkPreview = `-- name: GetLinkPreview :one
+SELECT url, fetched_at, status, title, description, image_url, site_name FROM link_previews
+WHERE url = $1
+`
+
+func (q *Queries) GetLinkPreview(ctx context.Context, url string) (LinkPreview, error) {
+	row := q.db.QueryRowContext(ctx, getLinkPreview, url)
+	var i LinkPreview
+	err := row.Scan(
+		&i.Url,
+		&i.FetchedAt,
+		&i.Status,
+		&i.Title,
+		&i.Description,
+		&i.ImageUrl,
+		&i.SiteName,
+	)
+	return i, err
+}
+
+const listLinkPreviewsByURLs = `-- name: ListLinkPreviewsByURLs :many
+SELECT url, fetched_at, status, title, description, image_url, site_name FROM link_previews
+WHERE url = ANY($1::text[])
+AND status = 'ok'
+`
+
+func (q *Queries) ListLinkPreviewsByURLs(ctx context.Context, urls []string) ([]LinkPreview, error) {
+	rows, err := q.db.QueryContext(ctx, listLinkPreviewsByURLs, pq.Array(urls))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LinkPreview
+	for rows.Next() {
+		var i LinkPreview
+		if err := rows.Scan(
+			&i.Url,
+			&i.FetchedAt,
+			&i.Status,
+			&i.Title,
+			&i.Description,
+			&i.ImageUrl,
+			&i.SiteName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertLinkPreview = `-- name: UpsertLinkPreview :one
+INSERT INTO link_previews (url, fetched_at, status, title, description, image_url, site_name)
+VALUES (
+    $1, NOW(), $2, $3, $4, $5, $6
+)
+ON CONFLICT (url) DO UPDATE SET fetched_at = NOW(),
+status = EXCLUDED.status,
+title = EXCLUDED.title,
+description = EXCLUDED.description,
+image_url = EXCLUDED.image_url,
+site_name = EXCLUDED.site_name
+RETURNING url, fetched_at, status, title, description, image_url, site_name
+`
+
+type UpsertLinkPreviewParams struct {
+	Url         string
+	Status      string
+	Title       string
+	Description string
+	ImageUrl    string
+	SiteName    string
+}
+
+func (q *Queries) UpsertLinkPreview(ctx context.Context, arg UpsertLinkPreviewParams) (LinkPreview, error) {
+	row := q.db.QueryRowContext(ctx, upsertLinkPreview,
+		arg.Url,
+		arg.Status,
+		arg.Title,
+		arg.Description,
+		arg.ImageUrl,
+		arg.SiteName,
+	)
+	var i LinkPreview
+	err := row.Scan(
+		&i.Url,
+		&i.FetchedAt,
+		&i.Status,
+		&i.Title,
+		&i.Description,
+		&i.ImageUrl,
+		&i.SiteName,
+	)
+	return i, err
+}
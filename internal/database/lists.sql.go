@@ -0,0 +1,475 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: lists.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const addListMember = `-- name: AddListMember :one
+INSERT INTO list_members (list_id, user_id, added_at)
+SELECT lists.id, $1::uuid, NOW()
+FROM lists
+WHERE lists.id = $2::uuid
+AND lists.owner_id = $3::uuid
+RETURNING list_id, user_id, added_at
+`
+
+type AddListMemberParams struct {
+	UserID  uuid.UUID
+	ListID  uuid.UUID
+	OwnerID uuid.UUID
+}
+
+// only the owner can add members, returns no rows otherwise
+func (q *Queries) AddListMember(ctx context.Context, arg AddListMemberParams) (ListMember, error) {
+	row := q.db.QueryRowContext(ctx, addListMember, arg.UserID, arg.ListID, arg.OwnerID)
+	var i ListMember
+	err := row.Scan(&i.ListID, &i.UserID, &i.AddedAt)
+	return i, err
+}
+
+const countListMembers = `-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1
+`
+
+func (q *Queries) CountListMembers(ctx context.Context, listID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countListMembers, listID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createList = `-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, owner_id, name, description, is_private)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+RETURNING id, created_at, updated_at, owner_id, name, description, is_private
+`
+
+type CreateListParams struct {
+	OwnerID     uuid.UUID
+	Name        string
+	Description string
+	IsPrivate   bool
+}
+
+func (q *Queries) CreateList(ctx context.Context, arg CreateListParams) (List, error) {
+	row := q.db.QueryRowContext(ctx, createList,
+		arg.OwnerID,
+		arg.Name,
+		arg.Description,
+		arg.IsPrivate,
+	)
+	var i List
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.Name,
+		&i.Description,
+		&i.IsPrivate,
+	)
+	return i, err
+}
+
+const deleteList = `-- name: DeleteList :one
+DELETE FROM lists
+WHERE id = $1
+AND owner_id = $2
+RETURNING id, created_at, updated_at, owner_id, name, description, is_private
+`
+
+type DeleteListParams struct {
+	ID      uuid.UUID
+	OwnerID uuid.UUID
+}
+
+func (q *Queries) DeleteList(ctx context.Context, arg DeleteListParams) (List, error) {
+	row := q.db.QueryRowContext(ctx, deleteList, arg.ID, arg.OwnerID)
+	var i List
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.Name,
+		&i.Description,
+		&i.IsPrivate,
+	)
+	return i, err
+}
+
+const getListForViewer = `-- name: GetListForViewer :one
+SELECT lists.id, lists.created_at, lists.updated_at, lists.owner_id, lists.name, lists.description, lists.is_private,
+(SELECT COUNT(*) FROM list_members WHERE list_members.list_id = lists.id) AS member_count,
+(SELECT COUNT(*) FROM list_subscriptions WHERE list_subscriptions.list_id = lists.id) AS subscriber_count,
+EXISTS (
+    SELECT 1 FROM list_subscriptions
+    WHERE list_subscriptions.list_id = lists.id
+    AND list_subscriptions.user_id = $1
+) AS subscribed
+FROM lists
+WHERE lists.id = $2
+AND (lists.is_private = FALSE OR lists.owner_id = $1)
+`
+
+type GetListForViewerParams struct {
+	ViewerID uuid.UUID
+	ListID   uuid.UUID
+}
+
+type GetListForViewerRow struct {
+	List            List
+	MemberCount     int64
+	SubscriberCount int64
+	Subscribed      bool
+}
+
+// private lists only exist for their owner
+func (q *Queries) GetListForViewer(ctx context.Context, arg GetListForViewerParams) (GetListForViewerRow, error) {
+	row := q.db.QueryRowContext(ctx, getListForViewer, arg.ViewerID, arg.ListID)
+	var i GetListForViewerRow
+	err := row.Scan(
+		&i.List.ID,
+		&i.List.CreatedAt,
+		&i.List.UpdatedAt,
+		&i.List.OwnerID,
+		&i.List.Name,
+		&i.List.Description,
+		&i.List.IsPrivate,
+		&i.MemberCount,
+		&i.SubscriberCount,
+		&i.Subscribed,
+	)
+	return i, err
+}
+
+const listListChirps = `-- name: ListListChirps :many
+SELECT chirps.chirp_id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.hidden_at, chirps.deleted_at, chirps.publish_at, chirps.rechirp_of, chirps.quote_of FROM chirps
+JOIN list_members ON list_members.user_id = chirps.user_id
+JOIN users ON users.id = chirps.user_id
+WHERE list_members.list_id = $1
+AND ($2::timestamp IS NULL OR chirps.created_at < $2::timestamp)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $3)
+AND (chirps.publish_at IS NULL OR chirps.user_id = $3)
+AND chirps.deleted_at IS NULL
+AND (chirps.rechirp_of IS NULL OR EXISTS (
+    SELECT 1 FROM chirps original
+    JOIN users original_author ON original_author.id = original.user_id
+    WHERE original.chirp_id = chirps.rechirp_of
+    AND original.deleted_at IS NULL
+    AND original.hidden_at IS NULL
+    AND original.publish_at IS NULL
+    AND original_author.chirps_hidden = FALSE
+    AND original_author.deletion_requested_at IS NULL
+))
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = $3
+    AND user_blocks.blocked_id IN (chirps.user_id, (SELECT original.user_id FROM chirps original WHERE original.chirp_id = chirps.rechirp_of))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE user_mutes.muter_id = $3
+    AND user_mutes.muted_id IN (chirps.user_id, (SELECT original.user_id FROM chirps original WHERE original.chirp_id = chirps.rechirp_of))
+)
+ORDER BY chirps.created_at DESC
+LIMIT $4
+`
+
+type ListListChirpsParams struct {
+	ListID     uuid.UUID
+	Before     sql.NullTime
+	ViewerID   uuid.UUID
+	MaxResults int32
+}
+
+// the list's members' chirps, newest first, with the same visibility rules
+// as ListChirps
+// rechirps go away with the chirp they share, by the same rules as
+// ListEmbeddableChirps
+func (q *Queries) ListListChirps(ctx context.Context, arg ListListChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listListChirps,
+		arg.ListID,
+		arg.Before,
+		arg.ViewerID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listListMembers = `-- name: ListListMembers :many
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.role, users.suspended_until, users.suspension_reason, users.banned_at, users.ban_reason, users.chirps_hidden, users.deletion_requested_at, users.delete_after, users.handle, users.display_name, users.bio, users.avatar_url FROM list_members
+JOIN users ON users.id = list_members.user_id
+WHERE list_members.list_id = $1
+ORDER BY list_members.added_at ASC
+`
+
+func (q *Queries) ListListMembers(ctx context.Context, listID uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listListMembers, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.Role,
+			&i.SuspendedUntil,
+			&i.SuspensionReason,
+			&i.BannedAt,
+			&i.BanReason,
+			&i.ChirpsHidden,
+			&i.DeletionRequestedAt,
+			&i.DeleteAfter,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listListsForUser = `-- name: ListListsForUser :many
+SELECT lists.id, lists.created_at, lists.updated_at, lists.owner_id, lists.name, lists.description, lists.is_private,
+(SELECT COUNT(*) FROM list_members WHERE list_members.list_id = lists.id) AS member_count,
+(SELECT COUNT(*) FROM list_subscriptions WHERE list_subscriptions.list_id = lists.id) AS subscriber_count,
+EXISTS (
+    SELECT 1 FROM list_subscriptions
+    WHERE list_subscriptions.list_id = lists.id
+    AND list_subscriptions.user_id = $1
+) AS subscribed
+FROM lists
+WHERE lists.owner_id = $1
+OR (lists.is_private = FALSE AND EXISTS (
+    SELECT 1 FROM list_subscriptions
+    WHERE list_subscriptions.list_id = lists.id
+    AND list_subscriptions.user_id = $1
+))
+ORDER BY lists.name ASC
+`
+
+type ListListsForUserRow struct {
+	List            List
+	MemberCount     int64
+	SubscriberCount int64
+	Subscribed      bool
+}
+
+// the user's own lists and the public lists they subscribe to
+func (q *Queries) ListListsForUser(ctx context.Context, userID uuid.UUID) ([]ListListsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listListsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListListsForUserRow
+	for rows.Next() {
+		var i ListListsForUserRow
+		if err := rows.Scan(
+			&i.List.ID,
+			&i.List.CreatedAt,
+			&i.List.UpdatedAt,
+			&i.List.OwnerID,
+			&i.List.Name,
+			&i.List.Description,
+			&i.List.IsPrivate,
+			&i.MemberCount,
+			&i.SubscriberCount,
+			&i.Subscribed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockListForMembers = `-- name: LockListForMembers :one
+SELECT id FROM lists
+WHERE id = $1
+AND owner_id = $2
+FOR UPDATE
+`
+
+type LockListForMembersParams struct {
+	ListID  uuid.UUID
+	OwnerID uuid.UUID
+}
+
+// serializes adding members per list so two requests can't both squeeze
+// under the limit, returns no rows unless owner_id owns the list
+func (q *Queries) LockListForMembers(ctx context.Context, arg LockListForMembersParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, lockListForMembers, arg.ListID, arg.OwnerID)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const removeListMember = `-- name: RemoveListMember :one
+DELETE FROM list_members
+USING lists
+WHERE list_members.list_id = lists.id
+AND lists.id = $1
+AND lists.owner_id = $2
+AND list_members.user_id = $3
+RETURNING list_members.list_id, list_members.user_id, list_members.added_at
+`
+
+type RemoveListMemberParams struct {
+	ListID  uuid.UUID
+	OwnerID uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (q *Queries) RemoveListMember(ctx context.Context, arg RemoveListMemberParams) (ListMember, error) {
+	row := q.db.QueryRowContext(ctx, removeListMember, arg.ListID, arg.OwnerID, arg.UserID)
+	var i ListMember
+	err := row.Scan(&i.ListID, &i.UserID, &i.AddedAt)
+	return i, err
+}
+
+const subscribeToList = `-- name: SubscribeToList :one
+INSERT INTO list_subscriptions (list_id, user_id, created_at)
+SELECT lists.id, $1::uuid, NOW()
+FROM lists
+WHERE lists.id = $2::uuid
+AND lists.is_private = FALSE
+AND lists.owner_id <> $1::uuid
+RETURNING list_id, user_id, created_at
+`
+
+type SubscribeToListParams struct {
+	UserID uuid.UUID
+	ListID uuid.UUID
+}
+
+// public lists of other users only
+func (q *Queries) SubscribeToList(ctx context.Context, arg SubscribeToListParams) (ListSubscription, error) {
+	row := q.db.QueryRowContext(ctx, subscribeToList, arg.UserID, arg.ListID)
+	var i ListSubscription
+	err := row.Scan(&i.ListID, &i.UserID, &i.CreatedAt)
+	return i, err
+}
+
+const unsubscribeFromList = `-- name: UnsubscribeFromList :one
+DELETE FROM list_subscriptions
+WHERE list_id = $1
+AND user_id = $2
+RETURNING list_id, user_id, created_at
+`
+
+type UnsubscribeFromListParams struct {
+	ListID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) UnsubscribeFromList(ctx context.Context, arg UnsubscribeFromListParams) (ListSubscription, error) {
+	row := q.db.QueryRowContext(ctx, unsubscribeFromList, arg.ListID, arg.UserID)
+	var i ListSubscription
+	err := row.Scan(&i.ListID, &i.UserID, &i.CreatedAt)
+	return i, err
+}
+
+const updateList = `-- name: UpdateList :one
+UPDATE lists SET name = $3,
+description = $4,
+is_private = $5,
+updated_at = NOW()
+WHERE id = $1
+AND owner_id = $2
+RETURNING id, created_at, updated_at, owner_id, name, description, is_private
+`
+
+type UpdateListParams struct {
+	ID          uuid.UUID
+	OwnerID     uuid.UUID
+	Name        string
+	Description string
+	IsPrivate   bool
+}
+
+func (q *Queries) UpdateList(ctx context.Context, arg UpdateListParams) (List, error) {
+	row := q.db.QueryRowContext(ctx, updateList,
+		arg.ID,
+		arg.OwnerID,
+		arg.Name,
+		arg.Description,
+		arg.IsPrivate,
+	)
+	var i List
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.Name,
+		&i.Description,
+		&i.IsPrivate,
+	)
+	return i, err
+}
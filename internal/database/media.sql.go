@@ -0,0 +1,190 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: media.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const attachMedia = `-- name: AttachMedia :one
+UPDATE media SET chirp_id = $1, position = $2
+WHERE id = $3
+AND user_id = $4
+AND chirp_id IS NULL
+RETURNING id, created_at, user_id, chirp_id, position, storage_key, content_type, size_bytes, width, height
+`
+
+type AttachMediaParams struct {
+	ChirpID  uuid.NullUUID
+	Position int32
+	ID       uuid.UUID
+	UserID   uuid.UUID
+}
+
+// only the uploader can attach a file, and only to one chirp
+func (q *Queries) AttachMedia(ctx context.Context, arg AttachMediaParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, attachMedia,
+		arg.ChirpID,
+		arg.Position,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Position,
+		&i.StorageKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+	)
+	return i, err
+}
+
+const createMedia = `-- name: CreateMedia :one
+INSERT INTO media (id, created_at, user_id, storage_key, content_type, size_bytes, width, height)
+VALUES (
+    $1, NOW(), $2, $3, $4, $5, $6, $7
+)
+RETURNING id, created_at, user_id, chirp_id, position, storage_key, content_type, size_bytes, width, height
+`
+
+type CreateMediaParams struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	StorageKey  string
+	ContentType string
+	SizeBytes   int64
+	Width       int32
+	Height      int32
+}
+
+func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, createMedia,
+		arg.ID,
+		arg.UserID,
+		arg.StorageKey,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.Width,
+		arg.Height,
+	)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Position,
+		&i.StorageKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+	)
+	return i, err
+}
+
+const getMediaByStorageKey = `-- name: GetMediaByStorageKey :one
+SELECT id, created_at, user_id, chirp_id, position, storage_key, content_type, size_bytes, width, height FROM media
+WHERE storage_key = $1
+`
+
+func (q *Queries) GetMediaByStorageKey(ctx context.Context, storageKey string) (Media, error) {
+	row := q.db.QueryRowContext(ctx, getMediaByStorageKey, storageKey)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Position,
+		&i.StorageKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+	)
+	return i, err
+}
+
+const listMediaByChirpIDs = `-- name: ListMediaByChirpIDs :many
+SELECT id, created_at, user_id, chirp_id, position, storage_key, content_type, size_bytes, width, height FROM media
+WHERE chirp_id = ANY($1::uuid[])
+ORDER BY chirp_id, position
+`
+
+func (q *Queries) ListMediaByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]Media, error) {
+	rows, err := q.db.QueryContext(ctx, listMediaByChirpIDs, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Media
+	for rows.Next() {
+		var i Media
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.ChirpID,
+			&i.Position,
+			&i.StorageKey,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeUnattachedMedia = `-- name: PurgeUnattachedMedia :many
+DELETE FROM media
+WHERE chirp_id IS NULL
+AND created_at < $1::timestamp
+RETURNING storage_key
+`
+
+func (q *Queries) PurgeUnattachedMedia(ctx context.Context, createdBefore time.Time) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, purgeUnattachedMedia, createdBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var storage_key string
+		if err := rows.Scan(&storage_key); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_key)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
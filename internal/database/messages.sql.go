@@ -0,0 +1,477 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: messages.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addConversationParticipant = `-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW())
+`
+
+type AddConversationParticipantParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) AddConversationParticipant(ctx context.Context, arg AddConversationParticipantParams) error {
+	_, err := q.db.ExecContext(ctx, addConversationParticipant, arg.ConversationID, arg.UserID)
+	return err
+}
+
+const blockMessagesFrom = `-- name: BlockMessagesFrom :exec
+INSERT INTO message_blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type BlockMessagesFromParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) BlockMessagesFrom(ctx context.Context, arg BlockMessagesFromParams) error {
+	_, err := q.db.ExecContext(ctx, blockMessagesFrom, arg.BlockerID, arg.BlockedID)
+	return err
+}
+
+const countUnreadMessages = `-- name: CountUnreadMessages :one
+SELECT COUNT(*) FROM messages
+JOIN conversation_participants me ON me.conversation_id = messages.conversation_id AND me.user_id = $1
+WHERE messages.conversation_id = $2
+AND messages.sender_id <> $1
+AND (me.last_read_at IS NULL OR messages.created_at > me.last_read_at)
+`
+
+type CountUnreadMessagesParams struct {
+	UserID         uuid.UUID
+	ConversationID uuid.UUID
+}
+
+// the same count as unread_count in ListConversationsForUser
+func (q *Queries) CountUnreadMessages(ctx context.Context, arg CountUnreadMessagesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUnreadMessages, arg.UserID, arg.ConversationID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createConversation = `-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, is_group)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2
+)
+RETURNING id, created_at, updated_at, created_by, is_group
+`
+
+type CreateConversationParams struct {
+	CreatedBy uuid.NullUUID
+	IsGroup   bool
+}
+
+func (q *Queries) CreateConversation(ctx context.Context, arg CreateConversationParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, createConversation, arg.CreatedBy, arg.IsGroup)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+		&i.IsGroup,
+	)
+	return i, err
+}
+
+const createMessage = `-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+SELECT gen_random_uuid(), NOW(), conversation_participants.conversation_id, conversation_participants.user_id, $1::text
+FROM conversation_participants
+WHERE conversation_participants.conversation_id = $2
+AND conversation_participants.user_id = $3
+RETURNING id, created_at, conversation_id, sender_id, body
+`
+
+type CreateMessageParams struct {
+	Body           string
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+}
+
+// only participants can post
+func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
+	row := q.db.QueryRowContext(ctx, createMessage, arg.Body, arg.ConversationID, arg.SenderID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+	)
+	return i, err
+}
+
+const findDirectConversation = `-- name: FindDirectConversation :one
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.created_by, conversations.is_group FROM conversations
+JOIN conversation_participants a ON a.conversation_id = conversations.id AND a.user_id = $1
+JOIN conversation_participants b ON b.conversation_id = conversations.id AND b.user_id = $2
+WHERE conversations.is_group = FALSE
+LIMIT 1
+`
+
+type FindDirectConversationParams struct {
+	UserA uuid.UUID
+	UserB uuid.UUID
+}
+
+func (q *Queries) FindDirectConversation(ctx context.Context, arg FindDirectConversationParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, findDirectConversation, arg.UserA, arg.UserB)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+		&i.IsGroup,
+	)
+	return i, err
+}
+
+const getConversationForUser = `-- name: GetConversationForUser :one
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.created_by, conversations.is_group FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversations.id = $1
+AND conversation_participants.user_id = $2
+`
+
+type GetConversationForUserParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+// every conversation query goes through the participants table, so nobody
+// outside a conversation can see it
+func (q *Queries) GetConversationForUser(ctx context.Context, arg GetConversationForUserParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, getConversationForUser, arg.ConversationID, arg.UserID)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+		&i.IsGroup,
+	)
+	return i, err
+}
+
+const isBlockedByAny = `-- name: IsBlockedByAny :one
+SELECT (EXISTS (
+    SELECT 1 FROM message_blocks
+    WHERE message_blocks.blocker_id = ANY($1::uuid[])
+    AND message_blocks.blocked_id = $2
+) OR EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = ANY($1::uuid[])
+    AND user_blocks.blocked_id = $2
+))::boolean
+`
+
+type IsBlockedByAnyParams struct {
+	UserIds  []uuid.UUID
+	SenderID uuid.UUID
+}
+
+// whether any of the users has blocked the sender
+func (q *Queries) IsBlockedByAny(ctx context.Context, arg IsBlockedByAnyParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlockedByAny, pq.Array(arg.UserIds), arg.SenderID)
+	var column_1 bool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const isBlockedFromConversation = `-- name: IsBlockedFromConversation :one
+SELECT (EXISTS (
+    SELECT 1 FROM message_blocks
+    JOIN conversation_participants ON conversation_participants.user_id = message_blocks.blocker_id
+    WHERE conversation_participants.conversation_id = $1
+    AND message_blocks.blocked_id = $2
+) OR EXISTS (
+    SELECT 1 FROM user_blocks
+    JOIN conversation_participants ON conversation_participants.user_id = user_blocks.blocker_id
+    WHERE conversation_participants.conversation_id = $1
+    AND user_blocks.blocked_id = $2
+))::boolean
+`
+
+type IsBlockedFromConversationParams struct {
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+}
+
+// whether anyone in the conversation has blocked the sender, a full block
+// covers messages too
+func (q *Queries) IsBlockedFromConversation(ctx context.Context, arg IsBlockedFromConversationParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlockedFromConversation, arg.ConversationID, arg.SenderID)
+	var column_1 bool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const listConversationParticipants = `-- name: ListConversationParticipants :many
+SELECT conversation_id, user_id, joined_at, last_read_at FROM conversation_participants
+WHERE conversation_id = ANY($1::uuid[])
+ORDER BY joined_at ASC
+`
+
+func (q *Queries) ListConversationParticipants(ctx context.Context, conversationIds []uuid.UUID) ([]ConversationParticipant, error) {
+	rows, err := q.db.QueryContext(ctx, listConversationParticipants, pq.Array(conversationIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ConversationParticipant
+	for rows.Next() {
+		var i ConversationParticipant
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.UserID,
+			&i.JoinedAt,
+			&i.LastReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationsForUser = `-- name: ListConversationsForUser :many
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.created_by, conversations.is_group,
+(
+    SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = conversations.id
+    AND messages.sender_id <> $1
+    AND (me.last_read_at IS NULL OR messages.created_at > me.last_read_at)
+) AS unread_count
+FROM conversations
+JOIN conversation_participants me ON me.conversation_id = conversations.id AND me.user_id = $1
+WHERE ($2::timestamp IS NULL OR conversations.updated_at < $2::timestamp)
+ORDER BY conversations.updated_at DESC
+LIMIT $3
+`
+
+type ListConversationsForUserParams struct {
+	UserID     uuid.UUID
+	Before     sql.NullTime
+	MaxResults int32
+}
+
+type ListConversationsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CreatedBy   uuid.NullUUID
+	IsGroup     bool
+	UnreadCount int64
+}
+
+func (q *Queries) ListConversationsForUser(ctx context.Context, arg ListConversationsForUserParams) ([]ListConversationsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listConversationsForUser, arg.UserID, arg.Before, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListConversationsForUserRow
+	for rows.Next() {
+		var i ListConversationsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CreatedBy,
+			&i.IsGroup,
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLastMessages = `-- name: ListLastMessages :many
+SELECT DISTINCT ON (conversation_id) id, created_at, conversation_id, sender_id, body FROM messages
+WHERE conversation_id = ANY($1::uuid[])
+ORDER BY conversation_id, created_at DESC
+`
+
+func (q *Queries) ListLastMessages(ctx context.Context, conversationIds []uuid.UUID) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, listLastMessages, pq.Array(conversationIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessages = `-- name: ListMessages :many
+SELECT messages.id, messages.created_at, messages.conversation_id, messages.sender_id, messages.body FROM messages
+JOIN conversation_participants ON conversation_participants.conversation_id = messages.conversation_id
+WHERE messages.conversation_id = $1
+AND conversation_participants.user_id = $2
+AND ($3::timestamp IS NULL OR messages.created_at < $3::timestamp)
+ORDER BY messages.created_at DESC
+LIMIT $4
+`
+
+type ListMessagesParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	Before         sql.NullTime
+	MaxResults     int32
+}
+
+func (q *Queries) ListMessages(ctx context.Context, arg ListMessagesParams) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, listMessages,
+		arg.ConversationID,
+		arg.UserID,
+		arg.Before,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockDirectConversation = `-- name: LockDirectConversation :exec
+SELECT pg_advisory_xact_lock(hashtextextended(
+    LEAST($1::uuid, $2::uuid)::text || GREATEST($1::uuid, $2::uuid)::text,
+    0
+))
+`
+
+type LockDirectConversationParams struct {
+	UserA uuid.UUID
+	UserB uuid.UUID
+}
+
+// serializes creating the direct conversation of two users until the end of
+// the transaction, whichever of them starts it
+func (q *Queries) LockDirectConversation(ctx context.Context, arg LockDirectConversationParams) error {
+	_, err := q.db.ExecContext(ctx, lockDirectConversation, arg.UserA, arg.UserB)
+	return err
+}
+
+const markConversationRead = `-- name: MarkConversationRead :one
+UPDATE conversation_participants SET last_read_at = NOW()
+WHERE conversation_id = $1
+AND user_id = $2
+RETURNING conversation_id, user_id, joined_at, last_read_at
+`
+
+type MarkConversationReadParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) (ConversationParticipant, error) {
+	row := q.db.QueryRowContext(ctx, markConversationRead, arg.ConversationID, arg.UserID)
+	var i ConversationParticipant
+	err := row.Scan(
+		&i.ConversationID,
+		&i.UserID,
+		&i.JoinedAt,
+		&i.LastReadAt,
+	)
+	return i, err
+}
+
+const touchConversation = `-- name: TouchConversation :exec
+UPDATE conversations SET updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) TouchConversation(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, touchConversation, id)
+	return err
+}
+
+const unblockMessagesFrom = `-- name: UnblockMessagesFrom :exec
+DELETE FROM message_blocks
+WHERE blocker_id = $1
+AND blocked_id = $2
+`
+
+type UnblockMessagesFromParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) UnblockMessagesFrom(ctx context.Context, arg UnblockMessagesFromParams) error {
+	_, err := q.db.ExecContext(ctx, unblockMessagesFrom, arg.BlockerID, arg.BlockedID)
+	return err
+}
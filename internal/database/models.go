@@ -28,6 +28,11 @@ type Chirp struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	Body      string    `json:"body"`
 	UserID    uuid.UUID `json:"user_id"`
+	HiddenAt  sql.NullTime `json:"-"`
+	DeletedAt sql.NullTime `json:"-"`
+	PublishAt sql.NullTime `json:"-"`
+	RechirpOf uuid.NullUUID `json:"-"`
+	QuoteOf   uuid.NullUUID `json:"-"`
 }
 
 type User struct {
@@ -35,6 +40,201 @@ type User struct {
 	CreatedAt      time.Time    `json:"created_at"`
 	UpdatedAt      time.Time    `json:"updated_at"`
 	Email          string       `json:"email"`
-	HashedPassword string       `json:"hashed_password"`
+	HashedPassword string       `json:"-"`
 	IsChirpyRed    bool         `json:"is_chirpy_red"`
+	Role           string       `json:"role"`
+	SuspendedUntil   sql.NullTime `json:"-"`
+	SuspensionReason string       `json:"-"`
+	BannedAt         sql.NullTime `json:"-"`
+	BanReason        string       `json:"-"`
+	ChirpsHidden     bool         `json:"-"`
+	DeletionRequestedAt sql.NullTime `json:"-"`
+	DeleteAfter         sql.NullTime `json:"-"`
+	Handle              sql.NullString `json:"-"`
+	DisplayName         string         `json:"display_name"`
+	Bio                 string         `json:"bio"`
+	AvatarUrl           string         `json:"avatar_url"`
+}
+
+type Report struct {
+	ID         uuid.UUID     `json:"id"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	ChirpID    uuid.UUID     `json:"chirp_id"`
+	ReporterID uuid.UUID     `json:"reporter_id"`
+	Reason     string        `json:"reason"`
+	Details    string        `json:"details"`
+	Status     string        `json:"status"`
+	ResolvedBy uuid.NullUUID `json:"resolved_by"`
+	ResolvedAt sql.NullTime  `json:"resolved_at"`
+}
+
+type ModerationAction struct {
+	ID           uuid.UUID     `json:"id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	ModeratorID  uuid.NullUUID `json:"moderator_id"`
+	Action       string        `json:"action"`
+	ChirpID      uuid.NullUUID `json:"chirp_id"`
+	ReportID     uuid.NullUUID `json:"report_id"`
+	TargetUserID uuid.NullUUID `json:"target_user_id"`
+	Reason       string        `json:"reason"`
+}
+
+type ExportJob struct {
+	ID          uuid.UUID    `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	UserID      uuid.UUID    `json:"user_id"`
+	Status      string       `json:"status"`
+	FilePath    string       `json:"-"`
+	Error       string       `json:"error"`
+	CompletedAt sql.NullTime `json:"-"`
+}
+
+type Media struct {
+	ID          uuid.UUID     `json:"id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UserID      uuid.UUID     `json:"user_id"`
+	ChirpID     uuid.NullUUID `json:"chirp_id"`
+	Position    int32         `json:"position"`
+	StorageKey  string        `json:"storage_key"`
+	ContentType string        `json:"content_type"`
+	SizeBytes   int64         `json:"size_bytes"`
+	Width       int32         `json:"width"`
+	Height      int32         `json:"height"`
+}
+
+type LinkPreview struct {
+	Url         string    `json:"url"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	Status      string    `json:"status"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	ImageUrl    string    `json:"image_url"`
+	SiteName    string    `json:"site_name"`
+}
+
+type ShortLink struct {
+	Code      string    `json:"code"`
+	CreatedAt time.Time `json:"created_at"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Url       string    `json:"url"`
+	Clicks    int64     `json:"clicks"`
+}
+
+type Draft struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Body      string    `json:"body"`
+}
+
+type Notification struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UserID    uuid.UUID     `json:"user_id"`
+	Type      string        `json:"type"`
+	ActorID   uuid.NullUUID `json:"actor_id"`
+	ChirpID   uuid.NullUUID `json:"chirp_id"`
+	Detail    string        `json:"detail"`
+	ReadAt    sql.NullTime  `json:"read_at"`
+}
+
+type NotificationMute struct {
+	UserID uuid.UUID `json:"user_id"`
+	Type   string    `json:"type"`
+}
+
+type Conversation struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	CreatedBy uuid.NullUUID `json:"created_by"`
+	IsGroup   bool          `json:"is_group"`
+}
+
+type ConversationParticipant struct {
+	ConversationID uuid.UUID    `json:"conversation_id"`
+	UserID         uuid.UUID    `json:"user_id"`
+	JoinedAt       time.Time    `json:"joined_at"`
+	LastReadAt     sql.NullTime `json:"last_read_at"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+type MessageBlock struct {
+	BlockerID uuid.UUID `json:"blocker_id"`
+	BlockedID uuid.UUID `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Bookmark struct {
+	UserID       uuid.UUID     `json:"user_id"`
+	ChirpID      uuid.UUID     `json:"chirp_id"`
+	CollectionID uuid.NullUUID `json:"collection_id"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+type BookmarkCollection struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+}
+
+type Poll struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ClosesAt  time.Time `json:"closes_at"`
+}
+
+type PollOption struct {
+	ID       uuid.UUID `json:"id"`
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	Position int32     `json:"position"`
+	Text     string    `json:"text"`
+}
+
+type PollVote struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	OptionID  uuid.UUID `json:"option_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type PinnedChirp struct {
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+type List struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsPrivate   bool      `json:"is_private"`
+}
+
+type ListMember struct {
+	ListID  uuid.UUID `json:"list_id"`
+	UserID  uuid.UUID `json:"user_id"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+type ListSubscription struct {
+	ListID    uuid.UUID `json:"list_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,136 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: pins.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const countPinnedChirps = `-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM pinned_chirps
+WHERE user_id = $1
+`
+
+func (q *Queries) CountPinnedChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPinnedChirps, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const listPinnedChirps = `-- name: ListPinnedChirps :many
+SELECT chirps.chirp_id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.hidden_at, chirps.deleted_at, chirps.publish_at, chirps.rechirp_of, chirps.quote_of FROM pinned_chirps
+JOIN chirps ON chirps.chirp_id = pinned_chirps.chirp_id
+JOIN users ON users.id = chirps.user_id
+WHERE pinned_chirps.user_id = $1
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $2)
+AND chirps.deleted_at IS NULL
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = $2
+    AND user_blocks.blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE user_mutes.muter_id = $2
+    AND user_mutes.muted_id = chirps.user_id
+)
+ORDER BY pinned_chirps.pinned_at DESC
+`
+
+type ListPinnedChirpsParams struct {
+	AuthorID uuid.UUID
+	ViewerID uuid.UUID
+}
+
+// newest pin first, with the same visibility rules as ListChirps
+func (q *Queries) ListPinnedChirps(ctx context.Context, arg ListPinnedChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listPinnedChirps, arg.AuthorID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUserForPinning = `-- name: LockUserForPinning :exec
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+// serializes pinning per user so two requests can't both squeeze under
+// the limit
+func (q *Queries) LockUserForPinning(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockUserForPinning, id)
+	return err
+}
+
+const pinChirp = `-- name: PinChirp :one
+INSERT INTO pinned_chirps (chirp_id, user_id, pinned_at)
+VALUES ($1, $2, NOW())
+RETURNING chirp_id, user_id, pinned_at
+`
+
+type PinChirpParams struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (q *Queries) PinChirp(ctx context.Context, arg PinChirpParams) (PinnedChirp, error) {
+	row := q.db.QueryRowContext(ctx, pinChirp, arg.ChirpID, arg.UserID)
+	var i PinnedChirp
+	err := row.Scan(&i.ChirpID, &i.UserID, &i.PinnedAt)
+	return i, err
+}
+
+const unpinChirp = `-- name: UnpinChirp :one
+DELETE FROM pinned_chirps
+WHERE chirp_id = $1
+AND user_id = $2
+RETURNING chirp_id, user_id, pinned_at
+`
+
+type UnpinChirpParams struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (q *Queries) UnpinChirp(ctx context.Context, arg UnpinChirpParams) (PinnedChirp, error) {
+	row := q.db.QueryRowContext(ctx, unpinChirp, arg.ChirpID, arg.UserID)
+	var i PinnedChirp
+	err := row.Scan(&i.ChirpID, &i.UserID, &i.PinnedAt)
+	return i, err
+}
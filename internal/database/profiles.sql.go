@@ -0,0 +1,142 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: profiles.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url FROM users
+WHERE LOWER(handle) = LOWER($1)
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+	)
+	return i, err
+}
+
+const listUsersByIDs = `-- name: ListUsersByIDs :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url FROM users
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) ListUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.Role,
+			&i.SuspendedUntil,
+			&i.SuspensionReason,
+			&i.BannedAt,
+			&i.BanReason,
+			&i.ChirpsHidden,
+			&i.DeletionRequestedAt,
+			&i.DeleteAfter,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users SET handle = $5,
+display_name = $2,
+bio = $3,
+avatar_url = $4,
+updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url
+`
+
+type UpdateUserProfileParams struct {
+	ID          uuid.UUID
+	DisplayName string
+	Bio         string
+	AvatarUrl   string
+	Handle      sql.NullString
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.DisplayName,
+		arg.Bio,
+		arg.AvatarUrl,
+		arg.Handle,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+	)
+	return i, err
+}
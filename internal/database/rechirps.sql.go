@@ -0,0 +1,178 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: rechirps.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const countRechirps = `-- name: CountRechirps :many
+SELECT chirps.rechirp_of, COUNT(*) AS rechirps FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.rechirp_of = ANY($1::uuid[])
+AND chirps.hidden_at IS NULL
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+GROUP BY chirps.rechirp_of
+`
+
+type CountRechirpsRow struct {
+	RechirpOf uuid.NullUUID
+	Rechirps  int64
+}
+
+// only rechirps ListChirps shows to everyone count
+func (q *Queries) CountRechirps(ctx context.Context, chirpIds []uuid.UUID) ([]CountRechirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, countRechirps, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRechirpsRow
+	for rows.Next() {
+		var i CountRechirpsRow
+		if err := rows.Scan(&i.RechirpOf, &i.Rechirps); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (chirp_id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), '', $1, $2
+)
+RETURNING chirp_id, created_at, updated_at, body, user_id, hidden_at, deleted_at, publish_at, rechirp_of, quote_of
+`
+
+type CreateRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ChirpID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const listEmbeddableChirps = `-- name: ListEmbeddableChirps :many
+SELECT chirps.chirp_id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.hidden_at, chirps.deleted_at, chirps.publish_at, chirps.rechirp_of, chirps.quote_of FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.chirp_id = ANY($1::uuid[])
+AND chirps.hidden_at IS NULL
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = $2
+    AND user_blocks.blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE user_mutes.muter_id = $2
+    AND user_mutes.muted_id = chirps.user_id
+)
+`
+
+type ListEmbeddableChirpsParams struct {
+	ChirpIds []uuid.UUID
+	ViewerID uuid.UUID
+}
+
+// the chirps rechirps and quotes point at, as far as everyone may see them
+// and the viewer hasn't blocked or muted their author
+func (q *Queries) ListEmbeddableChirps(ctx context.Context, arg ListEmbeddableChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listEmbeddableChirps, pq.Array(arg.ChirpIds), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const undoRechirp = `-- name: UndoRechirp :one
+DELETE FROM chirps
+WHERE user_id = $1
+AND rechirp_of = $2
+AND deleted_at IS NULL
+RETURNING chirp_id, created_at, updated_at, body, user_id, hidden_at, deleted_at, publish_at, rechirp_of, quote_of
+`
+
+type UndoRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) UndoRechirp(ctx context.Context, arg UndoRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, undoRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ChirpID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
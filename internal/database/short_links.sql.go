@@ -0,0 +1,99 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: short_links.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createShortLink = `-- name: CreateShortLink :one
+INSERT INTO short_links (code, created_at, chirp_id, url)
+VALUES (
+    $1, NOW(), $2, $3
+)
+ON CONFLICT (code) DO NOTHING
+RETURNING code, created_at, chirp_id, url, clicks
+`
+
+type CreateShortLinkParams struct {
+	Code    string
+	ChirpID uuid.UUID
+	Url     string
+}
+
+// a clashing code returns no rows and the caller picks another one
+func (q *Queries) CreateShortLink(ctx context.Context, arg CreateShortLinkParams) (ShortLink, error) {
+	row := q.db.QueryRowContext(ctx, createShortLink, arg.Code, arg.ChirpID, arg.Url)
+	var i ShortLink
+	err := row.Scan(
+		&i.Code,
+		&i.CreatedAt,
+		&i.ChirpID,
+		&i.Url,
+		&i.Clicks,
+	)
+	return i, err
+}
+
+const followShortLink = `-- name: FollowShortLink :one
+UPDATE short_links SET clicks = clicks + 1
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE short_links.code = $1
+AND chirps.chirp_id = short_links.chirp_id
+AND chirps.hidden_at IS NULL
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+RETURNING short_links.url
+`
+
+// links only work while everyone may see their chirp, the same rules as
+// ListEmbeddableChirps
+func (q *Queries) FollowShortLink(ctx context.Context, code string) (string, error) {
+	row := q.db.QueryRowContext(ctx, followShortLink, code)
+	var url string
+	err := row.Scan(&url)
+	return url, err
+}
+
+const listShortLinksByChirpIDs = `-- name: ListShortLinksByChirpIDs :many
+SELECT code, created_at, chirp_id, url, clicks FROM short_links
+WHERE chirp_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ListShortLinksByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]ShortLink, error) {
+	rows, err := q.db.QueryContext(ctx, listShortLinksByChirpIDs, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ShortLink
+	for rows.Next() {
+		var i ShortLink
+		if err := rows.Scan(
+			&i.Code,
+			&i.CreatedAt,
+			&i.ChirpID,
+			&i.Url,
+			&i.Clicks,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,26 +7,75 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const banUser = `-- name: BanUser :one
+UPDATE users SET banned_at = NOW(),
+ban_reason = $2,
+chirps_hidden = $3,
+updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url
+`
+
+type BanUserParams struct {
+	ID           uuid.UUID
+	BanReason    string
+	ChirpsHidden bool
+}
+
+func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, banUser, arg.ID, arg.BanReason, arg.ChirpsHidden)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+	)
+	return i, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (chirp_id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (chirp_id, created_at, updated_at, body, user_id, publish_at, quote_of)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
 )
-RETURNING chirp_id, created_at, updated_at, body, user_id
+RETURNING chirp_id, created_at, updated_at, body, user_id, hidden_at, deleted_at, publish_at, rechirp_of, quote_of
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	PublishAt sql.NullTime
+	QuoteOf   uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.PublishAt,
+		arg.QuoteOf,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ChirpID,
@@ -34,6 +83,11 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
 	)
 	return i, err
 }
@@ -71,20 +125,21 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 }
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -93,16 +148,35 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
 	)
 	return i, err
 }
 
 const deleteChirp = `-- name: DeleteChirp :one
-DELETE FROM chirps
-WHERE chirp_id = $1
-RETURNING chirp_id, created_at, updated_at, body, user_id
+WITH unpinned AS (
+    DELETE FROM pinned_chirps
+    WHERE pinned_chirps.chirp_id = $1
+)
+UPDATE chirps SET deleted_at = NOW(),
+updated_at = NOW()
+WHERE chirps.chirp_id = $1
+AND chirps.deleted_at IS NULL
+RETURNING chirp_id, created_at, updated_at, body, user_id, hidden_at, deleted_at, publish_at, rechirp_of, quote_of
 `
 
+// deleting a chirp also unpins it
 func (q *Queries) DeleteChirp(ctx context.Context, chirpID uuid.UUID) (Chirp, error) {
 	row := q.db.QueryRowContext(ctx, deleteChirp, chirpID)
 	var i Chirp
@@ -112,13 +186,19 @@ func (q *Queries) DeleteChirp(ctx context.Context, chirpID uuid.UUID) (Chirp, er
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
 	)
 	return i, err
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT chirp_id, created_at, updated_at, body, user_id FROM chirps
+SELECT chirp_id, created_at, updated_at, body, user_id, hidden_at, deleted_at, publish_at, rechirp_of, quote_of FROM chirps
 WHERE chirp_id = $1
+AND deleted_at IS NULL
 `
 
 func (q *Queries) GetChirp(ctx context.Context, chirpID uuid.UUID) (Chirp, error) {
@@ -130,12 +210,53 @@ func (q *Queries) GetChirp(ctx context.Context, chirpID uuid.UUID) (Chirp, error
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
+const getChirpAuthor = `-- name: GetChirpAuthor :one
+SELECT user_id FROM chirps
+WHERE chirp_id = $1
+`
+
+// deleted chirps too, rechirps and quotes of them still name their author
+func (q *Queries) GetChirpAuthor(ctx context.Context, chirpID uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getChirpAuthor, chirpID)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const getChirpIncludingDeleted = `-- name: GetChirpIncludingDeleted :one
+SELECT chirp_id, created_at, updated_at, body, user_id, hidden_at, deleted_at, publish_at, rechirp_of, quote_of FROM chirps
+WHERE chirp_id = $1
+`
+
+func (q *Queries) GetChirpIncludingDeleted(ctx context.Context, chirpID uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpIncludingDeleted, chirpID)
+	var i Chirp
+	err := row.Scan(
+		&i.ChirpID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url FROM users
 WHERE email = $1
 `
 
@@ -149,12 +270,55 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
 	)
 	return i, err
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red FROM users
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.role, users.suspended_until, users.suspension_reason, users.banned_at, users.ban_reason, users.chirps_hidden, users.deletion_requested_at, users.delete_after, users.handle, users.display_name, users.bio, users.avatar_url FROM users
 JOIN refresh_tokens ON users.id = refresh_tokens.user_id
 WHERE refresh_tokens.token = $1
 AND revoked_at IS NULL
@@ -171,17 +335,256 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Us
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
 	)
 	return i, err
 }
 
+const isChirpListed = `-- name: IsChirpListed :one
+SELECT EXISTS (
+    SELECT 1 FROM chirps
+    JOIN users ON users.id = chirps.user_id
+    WHERE chirps.chirp_id = $1
+    AND chirps.hidden_at IS NULL
+    AND chirps.publish_at IS NULL
+    AND chirps.deleted_at IS NULL
+    AND (chirps.rechirp_of IS NULL OR EXISTS (
+        SELECT 1 FROM chirps original
+        JOIN users original_author ON original_author.id = original.user_id
+        WHERE original.chirp_id = chirps.rechirp_of
+        AND original.deleted_at IS NULL
+        AND original.hidden_at IS NULL
+        AND original.publish_at IS NULL
+        AND original_author.chirps_hidden = FALSE
+        AND original_author.deletion_requested_at IS NULL
+    ))
+    AND users.chirps_hidden = FALSE
+    AND users.deletion_requested_at IS NULL
+)
+`
+
+// whether ListChirps shows the chirp to everyone, before any viewer's blocks
+// and mutes
+func (q *Queries) IsChirpListed(ctx context.Context, chirpID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isChirpListed, chirpID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const liftSuspension = `-- name: LiftSuspension :one
+UPDATE users SET suspended_until = NULL,
+suspension_reason = '',
+updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url
+`
+
+func (q *Queries) LiftSuspension(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, liftSuspension, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+	)
+	return i, err
+}
+
+const listActiveRefreshTokens = `-- name: ListActiveRefreshTokens :many
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens
+WHERE user_id = $1
+AND revoked_at IS NULL
+AND expires_at > NOW()
+`
+
+func (q *Queries) ListActiveRefreshTokens(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveRefreshTokens, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.Token,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listChirps = `-- name: ListChirps :many
-SELECT chirp_id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at ASC
+SELECT chirps.chirp_id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.hidden_at, chirps.deleted_at, chirps.publish_at, chirps.rechirp_of, chirps.quote_of FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE (chirps.hidden_at IS NULL OR chirps.user_id = $1)
+AND (chirps.publish_at IS NULL OR chirps.user_id = $1)
+AND chirps.deleted_at IS NULL
+AND (chirps.rechirp_of IS NULL OR EXISTS (
+    SELECT 1 FROM chirps original
+    JOIN users original_author ON original_author.id = original.user_id
+    WHERE original.chirp_id = chirps.rechirp_of
+    AND original.deleted_at IS NULL
+    AND original.hidden_at IS NULL
+    AND original.publish_at IS NULL
+    AND original_author.chirps_hidden = FALSE
+    AND original_author.deletion_requested_at IS NULL
+))
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+AND ($2::uuid IS NULL OR chirps.user_id = $2::uuid)
+AND ($2::uuid IS NULL OR NOT EXISTS (
+    SELECT 1 FROM pinned_chirps
+    WHERE pinned_chirps.chirp_id = chirps.chirp_id
+))
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = $1
+    AND user_blocks.blocked_id IN (chirps.user_id, (SELECT original.user_id FROM chirps original WHERE original.chirp_id = chirps.rechirp_of))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE user_mutes.muter_id = $1
+    AND user_mutes.muted_id IN (chirps.user_id, (SELECT original.user_id FROM chirps original WHERE original.chirp_id = chirps.rechirp_of))
+)
+ORDER BY chirps.created_at ASC
+`
+
+type ListChirpsParams struct {
+	ViewerID uuid.UUID
+	AuthorID uuid.NullUUID
+}
+
+// rechirps go away with the chirp they share, by the same rules as
+// ListEmbeddableChirps
+// an author's pinned chirps come from ListPinnedChirps and go first
+// nothing from people the viewer blocked or muted, including rechirps of them
+func (q *Queries) ListChirps(ctx context.Context, arg ListChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirps, arg.ViewerID, arg.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByUserIncludingDeleted = `-- name: ListChirpsByUserIncludingDeleted :many
+SELECT chirp_id, created_at, updated_at, body, user_id, hidden_at, deleted_at, publish_at, rechirp_of, quote_of FROM chirps
+WHERE user_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListChirpsByUserIncludingDeleted(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByUserIncludingDeleted, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLiveChirpsByUser = `-- name: ListLiveChirpsByUser :many
+SELECT chirp_id, created_at, updated_at, body, user_id, hidden_at, deleted_at, publish_at, rechirp_of, quote_of FROM chirps
+WHERE user_id = $1
+AND deleted_at IS NULL
+AND hidden_at IS NULL
+AND publish_at IS NULL
 `
 
-func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, listChirps)
+// what live clients currently show of a user, to take down when a ban hides
+// their chirps
+func (q *Queries) ListLiveChirpsByUser(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listLiveChirpsByUser, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -195,6 +598,11 @@ func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.HiddenAt,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
 		); err != nil {
 			return nil, err
 		}
@@ -209,6 +617,52 @@ func (q *Queries) ListChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at < $1::timestamp
+`
+
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedChirps, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreDeletedChirp = `-- name: RestoreDeletedChirp :one
+UPDATE chirps SET deleted_at = NULL,
+updated_at = NOW()
+WHERE chirp_id = $1
+AND user_id = $2
+AND deleted_at > $3::timestamp
+RETURNING chirp_id, created_at, updated_at, body, user_id, hidden_at, deleted_at, publish_at, rechirp_of, quote_of
+`
+
+type RestoreDeletedChirpParams struct {
+	ChirpID      uuid.UUID
+	UserID       uuid.UUID
+	DeletedAfter time.Time
+}
+
+func (q *Queries) RestoreDeletedChirp(ctx context.Context, arg RestoreDeletedChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreDeletedChirp, arg.ChirpID, arg.UserID, arg.DeletedAfter)
+	var i Chirp
+	err := row.Scan(
+		&i.ChirpID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+	)
+	return i, err
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :one
 UPDATE refresh_tokens SET revoked_at = NOW(),
 updated_at = NOW()
@@ -230,11 +684,162 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) (Refresh
 	return i, err
 }
 
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users SET role = $2,
+updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+	)
+	return i, err
+}
+
+const setUserRoleByEmail = `-- name: SetUserRoleByEmail :one
+UPDATE users SET role = $2,
+updated_at = NOW()
+WHERE email = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url
+`
+
+type SetUserRoleByEmailParams struct {
+	Email string
+	Role  string
+}
+
+func (q *Queries) SetUserRoleByEmail(ctx context.Context, arg SetUserRoleByEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRoleByEmail, arg.Email, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users SET suspended_until = $2,
+suspension_reason = $3,
+updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url
+`
+
+type SuspendUserParams struct {
+	ID               uuid.UUID
+	SuspendedUntil   sql.NullTime
+	SuspensionReason string
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, arg.ID, arg.SuspendedUntil, arg.SuspensionReason)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+	)
+	return i, err
+}
+
+const unbanUser = `-- name: UnbanUser :one
+UPDATE users SET banned_at = NULL,
+ban_reason = '',
+chirps_hidden = FALSE,
+updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url
+`
+
+func (q *Queries) UnbanUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, unbanUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+	)
+	return i, err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users SET email = $1,
 hashed_password = $2
 WHERE id = $3
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url
 `
 
 type UpdateUserParams struct {
@@ -253,6 +858,18 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
 	)
 	return i, err
 }
@@ -260,7 +877,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 const upgradeChirpyRed = `-- name: UpgradeChirpyRed :one
 UPDATE users SET is_chirpy_red = TRUE
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, role, suspended_until, suspension_reason, banned_at, ban_reason, chirps_hidden, deletion_requested_at, delete_after, handle, display_name, bio, avatar_url
 `
 
 func (q *Queries) UpgradeChirpyRed(ctx context.Context, id uuid.UUID) (User, error) {
@@ -273,6 +890,18 @@ func (q *Queries) UpgradeChirpyRed(ctx context.Context, id uuid.UUID) (User, err
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.BannedAt,
+		&i.BanReason,
+		&i.ChirpsHidden,
+		&i.DeletionRequestedAt,
+		&i.DeleteAfter,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
 	)
 	return i, err
 }
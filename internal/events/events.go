@@ -0,0 +1,202 @@
+package events
+
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+	"github.com/google/uuid"
+)
+
+
+
+// Bus carries events to everyone listening on this instance. Memory is all
+// a single instance needs, Postgres relays events between instances.
+type Bus interface {
+	// Publish sends ev to every subscriber. It doesn't wait for them, and
+	// with Postgres the event may arrive shortly after Publish returns.
+	Publish(ev Event) error
+	Subscribe(buffer int) *Subscription
+	Unsubscribe(sub *Subscription)
+	// Since returns the events after id, or false if they are no longer
+	// all buffered
+	Since(id uint64) ([]Event, bool)
+}
+
+
+
+const (
+	ChirpCreated = "chirp_created"
+	ChirpDeleted = "chirp_deleted"
+	UserUpgraded = "user_upgraded"
+	// notifications are private, they only go to RecipientID
+	Notification = "notification"
+)
+
+
+// Event is something that happened which live clients may want to hear
+// about. Data is the JSON clients receive.
+type Event struct {
+	// ID orders events and is what SSE clients send back in Last-Event-ID,
+	// the bus assigns it on publish
+	ID             uint64          `json:"id"`
+	Type           string          `json:"type"`
+	CreatedAt      time.Time       `json:"created_at"`
+	// UserID is who the event is about, used to filter by author
+	UserID         uuid.UUID       `json:"user_id"`
+	// ThreadID is the chirp a conversation hangs off: the chirp itself, or
+	// the original for rechirps and quotes
+	ThreadID       uuid.UUID       `json:"thread_id"`
+	// OriginalUserID is the author of ThreadID for rechirps and quotes, so
+	// they can be filtered out along with that author's own chirps
+	OriginalUserID uuid.UUID       `json:"original_user_id"`
+	RecipientID    uuid.UUID       `json:"recipient_id"`
+	Data           json.RawMessage `json:"data"`
+}
+
+
+
+// Subscription receives events published after it was created. C is
+// closed when the subscriber falls too far behind or unsubscribes.
+type Subscription struct {
+	C <-chan Event
+	c chan Event
+}
+
+
+
+// Memory is an in-process event bus. It keeps the last few events around so
+// a client that briefly lost its connection can catch up.
+type Memory struct {
+	mu     sync.Mutex
+	nextID uint64
+	replay []Event
+	size   int
+	subs   map[*Subscription]bool
+}
+
+
+// NewMemory returns a bus that remembers the last replaySize events.
+//
+// IDs start from the time the bus was created in microseconds, so an ID
+// handed out by another instance or before a restart falls outside this
+// bus's replay window and Since reports a gap, rather than replaying the
+// wrong events. Microseconds keep IDs below 2^53 for JavaScript clients.
+func NewMemory(replaySize int) *Memory {
+	return &Memory{
+		nextID: uint64(time.Now().UnixMicro()),
+		size:   replaySize,
+		subs:   map[*Subscription]bool{},
+	}
+}
+
+
+
+// Publish assigns the event an ID and hands it to every subscriber. It never
+// blocks: a subscriber whose buffer is full is dropped and has its channel
+// closed, and can resume from the replay buffer with Since.
+func (m *Memory) Publish(ev Event) error {
+	m.deliver(ev)
+	return nil
+}
+
+
+func (m *Memory) deliver(ev Event) Event {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ev.ID = m.nextID
+	m.nextID++
+	if ev.CreatedAt.IsZero() {
+		ev.CreatedAt = time.Now().UTC()
+	}
+
+	m.replay = append(m.replay, ev)
+	if len(m.replay) > m.size {
+		m.replay = m.replay[len(m.replay)-m.size:]
+	}
+
+	for sub := range m.subs {
+		select {
+		case sub.c <- ev:
+		default:
+			close(sub.c)
+			delete(m.subs, sub)
+		}
+	}
+
+	return ev
+}
+
+
+// Subscribe starts delivering events, buffering up to buffer of them for a
+// slow reader.
+func (m *Memory) Subscribe(buffer int) *Subscription {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := make(chan Event, buffer)
+	sub := &Subscription{C: c, c: c}
+	m.subs[sub] = true
+	return sub
+}
+
+
+func (m *Memory) Unsubscribe(sub *Subscription) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.subs[sub] {
+		close(sub.c)
+		delete(m.subs, sub)
+	}
+}
+
+
+// Since returns the events published after the one with the given ID. It
+// returns false if some of them have already left the replay buffer, in
+// which case the client has to reload instead.
+func (m *Memory) Since(id uint64) ([]Event, bool) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id >= m.nextID {
+		// an ID we never handed out, probably from before a restart
+		return nil, false
+	}
+
+	if id+1 == m.nextID {
+		return []Event{}, true
+	}
+
+	if len(m.replay) == 0 || m.replay[0].ID > id+1 {
+		return nil, false
+	}
+
+	start := int(id + 1 - m.replay[0].ID)
+	return append([]Event{}, m.replay[start:]...), true
+}
+
+
+
+// dropAll is for when events may have been lost, say while a connection
+// to Postgres was down. Every subscriber is disconnected and the replay
+// buffer emptied, so clients reconnect, find they can't resume and reload.
+func (m *Memory) dropAll() {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for sub := range m.subs {
+		close(sub.c)
+		delete(m.subs, sub)
+	}
+
+	m.replay = nil
+	// skip an ID so the last one handed out no longer counts as current
+	m.nextID++
+}
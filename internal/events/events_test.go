@@ -0,0 +1,116 @@
+package events
+
+
+import (
+	"testing"
+)
+
+
+
+func TestPublishSubscribe(t *testing.T) {
+
+	m := NewMemory(10)
+	sub := m.Subscribe(4)
+
+	m.Publish(Event{Type: ChirpCreated})
+	m.Publish(Event{Type: ChirpDeleted})
+
+	first := <-sub.C
+	second := <-sub.C
+	if first.Type != ChirpCreated || second.Type != ChirpDeleted || second.ID != first.ID+1 {
+		t.Fatalf("unexpected events %+v %+v", first, second)
+	}
+
+	m.Unsubscribe(sub)
+	if _, ok := <-sub.C; ok {
+		t.Fatal("channel should be closed after Unsubscribe")
+	}
+
+	// unsubscribing twice is fine
+	m.Unsubscribe(sub)
+}
+
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+
+	m := NewMemory(10)
+	slow := m.Subscribe(1)
+	fast := m.Subscribe(10)
+
+	for i := 0; i < 3; i++ {
+		m.Publish(Event{Type: ChirpCreated})
+	}
+
+	// the slow subscriber got the first event, then was cut off
+	<-slow.C
+	if _, ok := <-slow.C; ok {
+		t.Fatal("slow subscriber should have been closed")
+	}
+
+	if len(fast.C) != 3 {
+		t.Fatalf("fast subscriber should have 3 events, has %d", len(fast.C))
+	}
+}
+
+
+func TestSince(t *testing.T) {
+
+	m := NewMemory(3)
+	base := m.deliver(Event{Type: ChirpCreated}).ID
+
+	evs, ok := m.Since(base)
+	if !ok || len(evs) != 0 {
+		t.Fatalf("nothing new yet: got %v %v", evs, ok)
+	}
+
+	for i := 0; i < 4; i++ {
+		m.Publish(Event{Type: ChirpCreated})
+	}
+
+	// base+2, base+3 and base+4 are buffered
+	evs, ok = m.Since(base + 2)
+	if !ok || len(evs) != 2 || evs[0].ID != base+3 || evs[1].ID != base+4 {
+		t.Fatalf("Since(base+2) = %v %v", evs, ok)
+	}
+
+	evs, ok = m.Since(base + 1)
+	if !ok || len(evs) != 3 {
+		t.Fatalf("Since(base+1) = %v %v", evs, ok)
+	}
+
+	evs, ok = m.Since(base + 4)
+	if !ok || len(evs) != 0 {
+		t.Fatalf("Since(base+4) = %v %v", evs, ok)
+	}
+
+	// base+1 has left the buffer
+	if _, ok = m.Since(base); ok {
+		t.Fatal("Since(base) should report a gap")
+	}
+
+	// IDs from another instance or an earlier run
+	if _, ok = m.Since(base + 99); ok {
+		t.Fatal("an ID from the future should fail")
+	}
+	if _, ok = m.Since(1); ok {
+		t.Fatal("an ID from long ago should fail")
+	}
+}
+
+
+func TestDropAll(t *testing.T) {
+
+	m := NewMemory(10)
+	sub := m.Subscribe(4)
+	last := m.deliver(Event{Type: ChirpCreated}).ID
+	<-sub.C
+
+	m.dropAll()
+
+	if _, ok := <-sub.C; ok {
+		t.Fatal("subscribers should be disconnected")
+	}
+	if _, ok := m.Since(last); ok {
+		t.Fatal("a client that was up to date may have missed events and must reload")
+	}
+}
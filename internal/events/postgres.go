@@ -0,0 +1,116 @@
+package events
+
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+	"github.com/lib/pq"
+)
+
+
+
+// NOTIFY payloads must stay under 8000 bytes
+const maxPayload = 7900
+
+
+var ErrPayloadTooLarge = errors.New("event is too large to send with NOTIFY")
+
+
+// Postgres relays events between server instances with LISTEN/NOTIFY.
+// Publish only sends the NOTIFY; every instance, the sender included, hears
+// it back on its listener and hands it to local subscribers. IDs and the
+// replay buffer are therefore per instance, see NewMemory.
+type Postgres struct {
+	*Memory
+	db       *sql.DB
+	channel  string
+	listener *pq.Listener
+}
+
+
+
+// NewPostgres listens on channel using its own connection to connStr, and
+// publishes through db.
+func NewPostgres(db *sql.DB, connStr, channel string, replaySize int) (*Postgres, error) {
+
+	listener := pq.NewListener(connStr, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("event listener: %v", err)
+		}
+	})
+
+	err := listener.Listen(channel)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	p := &Postgres{
+		Memory:   NewMemory(replaySize),
+		db:       db,
+		channel:  channel,
+		listener: listener,
+	}
+
+	go p.run()
+
+	return p, nil
+}
+
+
+
+func (p *Postgres) Publish(ev Event) error {
+
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxPayload {
+		return ErrPayloadTooLarge
+	}
+
+	_, err = p.db.Exec("SELECT pg_notify($1, $2)", p.channel, string(payload))
+	return err
+}
+
+
+
+func (p *Postgres) run() {
+
+	for {
+		select {
+		case n, ok := <-p.listener.Notify:
+			if !ok {
+				// the listener was closed
+				return
+			}
+			if n == nil {
+				// the connection was re-established, anything sent while it
+				// was down is gone
+				p.dropAll()
+				continue
+			}
+
+			var ev Event
+			err := json.Unmarshal([]byte(n.Extra), &ev)
+			if err != nil {
+				log.Printf("event listener: bad payload: %v", err)
+				continue
+			}
+			p.deliver(ev)
+
+		case <-time.After(90 * time.Second):
+			// pq recommends pinging an idle listener to notice a dead
+			// connection
+			go p.listener.Ping()
+		}
+	}
+}
+
+
+func (p *Postgres) Close() error {
+	return p.listener.Close()
+}
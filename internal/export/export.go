@@ -0,0 +1,289 @@
+package export
+
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"time"
+	"github.com/google/uuid"
+)
+
+
+
+// Archive is everything we hold about a single user, as handed back to them
+// by a data export. Chirpy has no likes, so there are none to export.
+type Archive struct {
+	GeneratedAt         time.Time            `json:"generated_at"`
+	Profile             Profile              `json:"profile"`
+	Chirps              []Chirp              `json:"chirps"`
+	Drafts              []Draft              `json:"drafts"`
+	Sessions            []Session            `json:"sessions"`
+	Conversations       []Conversation       `json:"conversations"`
+	BookmarkCollections []BookmarkCollection `json:"bookmark_collections"`
+	Bookmarks           []Bookmark           `json:"bookmarks"`
+	PollVotes           []PollVote           `json:"poll_votes"`
+	Lists               []List               `json:"lists"`
+	ListSubscriptions   []ListSubscription   `json:"list_subscriptions"`
+	Blocks              []Relation           `json:"blocks"`
+	Mutes               []Relation           `json:"mutes"`
+	MessageBlocks       []Relation           `json:"message_blocks"`
+}
+
+
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	Role        string    `json:"role"`
+}
+
+
+type Chirp struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+
+type Draft struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+}
+
+
+// Session describes a refresh token without the token itself.
+type Session struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+
+// Conversation holds every message in it, including the ones other
+// participants sent, since that is what the user could read.
+type Conversation struct {
+	ID           uuid.UUID   `json:"id"`
+	CreatedAt    time.Time   `json:"created_at"`
+	IsGroup      bool        `json:"is_group"`
+	Participants []uuid.UUID `json:"participants"`
+	Messages     []Message   `json:"messages"`
+}
+
+
+type Message struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	SenderID  uuid.UUID `json:"sender_id"`
+	Body      string    `json:"body"`
+}
+
+
+type BookmarkCollection struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+}
+
+
+// Bookmark names its collection, empty when it isn't in one.
+type Bookmark struct {
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Collection string    `json:"collection"`
+}
+
+
+type PollVote struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Option    string    `json:"option"`
+}
+
+
+// List is a list the user owns, subscriptions to other lists are in
+// ListSubscription.
+type List struct {
+	ID          uuid.UUID   `json:"id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	IsPrivate   bool        `json:"is_private"`
+	Members     []uuid.UUID `json:"members"`
+}
+
+
+type ListSubscription struct {
+	ListID    uuid.UUID `json:"list_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+
+// Relation is another user the user has blocked or muted.
+type Relation struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+
+
+// WriteZip writes the archive as a zip file holding one JSON document with
+// everything, plus a CSV file per list for people who want a spreadsheet.
+func WriteZip(w io.Writer, a Archive) error {
+
+	zw := zip.NewWriter(w)
+
+	err := writeJSON(zw, "data.json", a)
+	if err != nil {
+		return err
+	}
+
+	chirpRows := [][]string{{"id", "created_at", "updated_at", "deleted_at", "body"}}
+	for _, c := range a.Chirps {
+		chirpRows = append(chirpRows, []string{
+			c.ID.String(),
+			formatTime(c.CreatedAt),
+			formatTime(c.UpdatedAt),
+			formatTimePtr(c.DeletedAt),
+			c.Body,
+		})
+	}
+
+	err = writeCSV(zw, "chirps.csv", chirpRows)
+	if err != nil {
+		return err
+	}
+
+	draftRows := [][]string{{"id", "created_at", "updated_at", "body"}}
+	for _, d := range a.Drafts {
+		draftRows = append(draftRows, []string{
+			d.ID.String(),
+			formatTime(d.CreatedAt),
+			formatTime(d.UpdatedAt),
+			d.Body,
+		})
+	}
+
+	err = writeCSV(zw, "drafts.csv", draftRows)
+	if err != nil {
+		return err
+	}
+
+	sessionRows := [][]string{{"created_at", "expires_at", "revoked_at"}}
+	for _, s := range a.Sessions {
+		sessionRows = append(sessionRows, []string{
+			formatTime(s.CreatedAt),
+			formatTime(s.ExpiresAt),
+			formatTimePtr(s.RevokedAt),
+		})
+	}
+
+	err = writeCSV(zw, "sessions.csv", sessionRows)
+	if err != nil {
+		return err
+	}
+
+	messageRows := [][]string{{"conversation_id", "id", "created_at", "sender_id", "body"}}
+	for _, c := range a.Conversations {
+		for _, m := range c.Messages {
+			messageRows = append(messageRows, []string{
+				c.ID.String(),
+				m.ID.String(),
+				formatTime(m.CreatedAt),
+				m.SenderID.String(),
+				m.Body,
+			})
+		}
+	}
+
+	err = writeCSV(zw, "messages.csv", messageRows)
+	if err != nil {
+		return err
+	}
+
+	bookmarkRows := [][]string{{"chirp_id", "created_at", "collection"}}
+	for _, b := range a.Bookmarks {
+		bookmarkRows = append(bookmarkRows, []string{
+			b.ChirpID.String(),
+			formatTime(b.CreatedAt),
+			b.Collection,
+		})
+	}
+
+	err = writeCSV(zw, "bookmarks.csv", bookmarkRows)
+	if err != nil {
+		return err
+	}
+
+	voteRows := [][]string{{"chirp_id", "created_at", "updated_at", "option"}}
+	for _, v := range a.PollVotes {
+		voteRows = append(voteRows, []string{
+			v.ChirpID.String(),
+			formatTime(v.CreatedAt),
+			formatTime(v.UpdatedAt),
+			v.Option,
+		})
+	}
+
+	err = writeCSV(zw, "poll_votes.csv", voteRows)
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+
+func writeCSV(zw *zip.Writer, name string, rows [][]string) error {
+
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(f)
+	err = cw.WriteAll(rows)
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
@@ -0,0 +1,103 @@
+package export
+
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+	"github.com/google/uuid"
+)
+
+
+
+
+func TestWriteZip(t *testing.T) {
+
+	now := time.Now().UTC().Truncate(time.Second)
+	deleted := now.Add(-time.Hour)
+
+	a := Archive{
+		GeneratedAt: now,
+		Profile:     Profile{ID: uuid.New(), Email: "walt@breakingbad.com", Role: "user"},
+		Chirps: []Chirp{
+			{ID: uuid.New(), CreatedAt: now, Body: "hello, \"world\""},
+			{ID: uuid.New(), CreatedAt: now, Body: "gone", DeletedAt: &deleted},
+		},
+		Drafts:   []Draft{{ID: uuid.New(), CreatedAt: now, Body: "later"}},
+		Sessions: []Session{{CreatedAt: now, ExpiresAt: now.Add(time.Hour)}},
+		Conversations: []Conversation{{
+			ID:       uuid.New(),
+			Messages: []Message{{ID: uuid.New(), CreatedAt: now, Body: "hi"}, {ID: uuid.New(), CreatedAt: now, Body: "hey"}},
+		}},
+		Bookmarks: []Bookmark{{ChirpID: uuid.New(), CreatedAt: now, Collection: "recipes"}},
+		PollVotes: []PollVote{{ChirpID: uuid.New(), CreatedAt: now, UpdatedAt: now, Option: "blue"}},
+	}
+
+	var buf bytes.Buffer
+	err := WriteZip(&buf, a)
+	if err != nil {
+		t.Fatalf("WriteZip failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a valid zip: %v", err)
+	}
+
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("couldn't open %s: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = b
+	}
+
+	var got Archive
+	err = json.Unmarshal(files["data.json"], &got)
+	if err != nil {
+		t.Fatalf("data.json is not valid json: %v", err)
+	}
+	if got.Profile.Email != a.Profile.Email || len(got.Chirps) != 2 {
+		t.Fatalf("data.json doesn't match the archive")
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(files["chirps.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("chirps.csv is not valid csv: %v", err)
+	}
+	if len(rows) != 3 || rows[1][4] != "hello, \"world\"" || rows[2][3] == "" {
+		t.Fatalf("unexpected chirps.csv rows: %v", rows)
+	}
+
+	rows, err = csv.NewReader(bytes.NewReader(files["sessions.csv"])).ReadAll()
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("unexpected sessions.csv: %v %v", rows, err)
+	}
+
+	rows, err = csv.NewReader(bytes.NewReader(files["drafts.csv"])).ReadAll()
+	if err != nil || len(rows) != 2 || rows[1][3] != "later" {
+		t.Fatalf("unexpected drafts.csv: %v %v", rows, err)
+	}
+
+	rows, err = csv.NewReader(bytes.NewReader(files["messages.csv"])).ReadAll()
+	if err != nil || len(rows) != 3 || rows[2][4] != "hey" {
+		t.Fatalf("unexpected messages.csv: %v %v", rows, err)
+	}
+
+	rows, err = csv.NewReader(bytes.NewReader(files["bookmarks.csv"])).ReadAll()
+	if err != nil || len(rows) != 2 || rows[1][2] != "recipes" {
+		t.Fatalf("unexpected bookmarks.csv: %v %v", rows, err)
+	}
+
+	rows, err = csv.NewReader(bytes.NewReader(files["poll_votes.csv"])).ReadAll()
+	if err != nil || len(rows) != 2 || rows[1][3] != "blue" {
+		t.Fatalf("unexpected poll_votes.csv: %v %v", rows, err)
+	}
+}
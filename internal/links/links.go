@@ -0,0 +1,98 @@
+package links
+
+
+import (
+	"crypto/rand"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+
+
+// URLLength is what every link counts as towards a chirp's length, however
+// long it really is. Links are shown shortened, so this is about the length
+// of a short link.
+const URLLength = 23
+
+
+// urlPattern finds http and https links in chirp bodies. Trailing
+// punctuation is trimmed afterwards, so "see https://boot.dev." works.
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+
+// findURLs returns the start and end of every link in body.
+func findURLs(body string) [][2]int {
+
+	spans := [][2]int{}
+	for _, loc := range urlPattern.FindAllStringIndex(body, -1) {
+		match := strings.TrimRight(body[loc[0]:loc[1]], ".,;:!?)]}'")
+		u, err := url.Parse(match)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		spans = append(spans, [2]int{loc[0], loc[0] + len(match)})
+	}
+	return spans
+}
+
+
+// FindURLs returns the links in a chirp body in the order they appear.
+func FindURLs(body string) []string {
+
+	urls := []string{}
+	for _, span := range findURLs(body) {
+		urls = append(urls, body[span[0]:span[1]])
+	}
+	return urls
+}
+
+
+// Length is the length of a chirp body as counted against the limit: one
+// per rune, except that each link counts as URLLength.
+func Length(body string) int {
+
+	n := 0
+	last := 0
+	for _, span := range findURLs(body) {
+		n += utf8.RuneCountInString(body[last:span[0]]) + URLLength
+		last = span[1]
+	}
+	return n + utf8.RuneCountInString(body[last:])
+}
+
+
+// Rewrite replaces every link in body with whatever replace returns for it.
+func Rewrite(body string, replace func(u string) string) string {
+
+	var b strings.Builder
+	last := 0
+	for _, span := range findURLs(body) {
+		b.WriteString(body[last:span[0]])
+		b.WriteString(replace(body[span[0]:span[1]]))
+		last = span[1]
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
+
+
+
+const codeAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+
+// NewCode returns a random short link code. Seven characters from 62 give
+// about 3.5 trillion codes, so clashes are rare and simply retried.
+func NewCode() string {
+
+	b := make([]byte, 7)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b)
+}
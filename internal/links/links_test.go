@@ -0,0 +1,200 @@
+package links
+
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+
+
+func TestFindURLs(t *testing.T) {
+
+	cases := []struct {
+		body string
+		want []string
+	}{
+		{"no links here", []string{}},
+		{"see https://boot.dev.", []string{"https://boot.dev"}},
+		{"(http://a.com/x?y=1) and https://b.org/path", []string{"http://a.com/x?y=1", "https://b.org/path"}},
+		{"ftp://files.example.com isn't a link", []string{}},
+	}
+
+	for _, c := range cases {
+		got := FindURLs(c.body)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("FindURLs(%q) = %v, want %v", c.body, got, c.want)
+		}
+	}
+}
+
+
+func TestLength(t *testing.T) {
+
+	long := "https://example.com/" + strings.Repeat("a", 200)
+
+	cases := []struct {
+		body string
+		want int
+	}{
+		{"hello", 5},
+		{"héllo wörld", 11},
+		{long, URLLength},
+		{"look: " + long + "!", 6 + URLLength + 1},
+		{long + " " + long, 2*URLLength + 1},
+	}
+
+	for _, c := range cases {
+		if got := Length(c.body); got != c.want {
+			t.Errorf("Length(%q) = %d, want %d", c.body, got, c.want)
+		}
+	}
+}
+
+
+func TestRewrite(t *testing.T) {
+
+	body := "a http://a.com then http://a.com/x, done"
+	got := Rewrite(body, func(u string) string {
+		return "<" + u + ">"
+	})
+	want := "a <http://a.com> then <http://a.com/x>, done"
+	if got != want {
+		t.Fatalf("Rewrite = %q, want %q", got, want)
+	}
+}
+
+
+
+const page = `<!doctype html>
+<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="Breaking Bad">
+<meta property="og:description" content="A chemistry teacher">
+<meta name="twitter:image" content="/img/card.png">
+<meta property="og:site_name" content="AMC">
+</head><body><meta property="og:title" content="ignored"></body></html>`
+
+
+// loopbackFetcher can reach httptest servers, which listen on 127.0.0.1.
+func loopbackFetcher() *Fetcher {
+	f := NewFetcher()
+	f.AllowIP = func(ip net.IP) bool { return true }
+	return f
+}
+
+
+
+func TestFetchPreview(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/show", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+
+	p, err := loopbackFetcher().Fetch(context.Background(), srv.URL+"/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Preview{
+		URL:         srv.URL + "/old",
+		Title:       "Breaking Bad",
+		Description: "A chemistry teacher",
+		ImageURL:    srv.URL + "/img/card.png",
+		SiteName:    "AMC",
+	}
+	if p != want {
+		t.Fatalf("got %+v, want %+v", p, want)
+	}
+}
+
+
+func TestFetchFallsBackToTitle(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Just a title</title><meta name="description" content="plain"></head></html>`)
+	}))
+	defer srv.Close()
+
+	p, err := loopbackFetcher().Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "Just a title" || p.Description != "plain" {
+		t.Fatalf("unexpected preview %+v", p)
+	}
+}
+
+
+
+func TestFetchBlocksPrivateAddresses(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the fetcher should never reach a loopback server")
+	}))
+	defer srv.Close()
+
+	_, err := NewFetcher().Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected ErrBlockedAddress, got %v", err)
+	}
+
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "100.64.0.1", "::1", "fd00::1", "0.0.0.0"} {
+		if PublicIP(net.ParseIP(ip)) {
+			t.Errorf("%s should not be public", ip)
+		}
+	}
+	if !PublicIP(net.ParseIP("93.184.216.34")) {
+		t.Errorf("93.184.216.34 should be public")
+	}
+}
+
+
+func TestFetchLimitsRedirects(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	_, err := loopbackFetcher().Fetch(context.Background(), srv.URL+"/")
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("expected ErrTooManyRedirects, got %v", err)
+	}
+}
+
+
+func TestFetchLimitsBodySize(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>"+strings.Repeat("<meta name=x content=y>", 1000))
+		fmt.Fprint(w, `<meta property="og:title" content="too far down"></head></html>`)
+	}))
+	defer srv.Close()
+
+	f := loopbackFetcher()
+	f.MaxBodyBytes = 1024
+
+	p, err := f.Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "" {
+		t.Fatalf("read past the body limit, got title %q", p.Title)
+	}
+}
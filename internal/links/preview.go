@@ -0,0 +1,232 @@
+package links
+
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"golang.org/x/net/html"
+)
+
+
+
+var ErrBlockedAddress = errors.New("address is not public")
+var ErrTooManyRedirects = errors.New("too many redirects")
+var ErrNotHTML = errors.New("not an html page")
+
+
+// Preview is the card shown under a chirp that links to a page.
+type Preview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
+}
+
+
+
+// Fetcher downloads pages for previews. Links come from users, so it only
+// ever connects to public addresses: the check happens when dialing, after
+// DNS resolution, so a hostname that resolves to 127.0.0.1 or one that is
+// rebound between lookups is caught too.
+type Fetcher struct {
+	MaxBodyBytes int64
+	MaxRedirects int
+	Timeout      time.Duration
+	// AllowIP decides which addresses may be dialed, PublicIP by default.
+	// Tests swap it out to reach an httptest server on loopback.
+	AllowIP func(ip net.IP) bool
+}
+
+
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		MaxBodyBytes: 512 << 10,
+		MaxRedirects: 3,
+		Timeout:      5 * time.Second,
+		AllowIP:      PublicIP,
+	}
+}
+
+
+
+// PublicIP reports whether ip is a routable internet address, rejecting
+// loopback, private, link local (which includes cloud metadata endpoints),
+// multicast and unspecified addresses.
+func PublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	// carrier grade NAT, 100.64.0.0/10
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xC0 == 64 {
+		return false
+	}
+	return true
+}
+
+
+
+func (f *Fetcher) client() *http.Client {
+
+	dialer := &net.Dialer{
+		Timeout: f.Timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !f.AllowIP(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: f.Timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   f.Timeout,
+			ResponseHeaderTimeout: f.Timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > f.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+
+
+// Fetch downloads rawURL and reads its OpenGraph and Twitter card tags,
+// falling back to the page title and meta description.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Preview{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Preview{}, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("User-Agent", "ChirpyBot/1.0 (link previews)")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return Preview{}, ErrNotHTML
+	}
+
+	p := Parse(io.LimitReader(resp.Body, f.MaxBodyBytes), resp.Request.URL)
+	p.URL = rawURL
+	return p, nil
+}
+
+
+
+// Parse reads preview metadata out of an html document. Relative image
+// links are resolved against base, the page's final URL.
+func Parse(r io.Reader, base *url.URL) Preview {
+
+	meta := map[string]string{}
+	title := ""
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return buildPreview(meta, title, base)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "meta":
+				key, content := "", ""
+				for _, a := range tok.Attr {
+					switch a.Key {
+					case "property", "name":
+						key = strings.ToLower(a.Val)
+					case "content":
+						content = strings.TrimSpace(a.Val)
+					}
+				}
+				if key != "" && content != "" && meta[key] == "" {
+					meta[key] = content
+				}
+			case "title":
+				inTitle = true
+			case "body":
+				// everything we want lives in <head>
+				return buildPreview(meta, title, base)
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(string(z.Text()))
+			}
+		case html.EndTagToken:
+			inTitle = false
+		}
+	}
+}
+
+
+func buildPreview(meta map[string]string, title string, base *url.URL) Preview {
+
+	first := func(values ...string) string {
+		for _, v := range values {
+			if v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	p := Preview{
+		Title:       first(meta["og:title"], meta["twitter:title"], title),
+		Description: first(meta["og:description"], meta["twitter:description"], meta["description"]),
+		SiteName:    meta["og:site_name"],
+	}
+
+	image := first(meta["og:image"], meta["og:image:url"], meta["twitter:image"])
+	if image != "" && base != nil {
+		ref, err := url.Parse(image)
+		if err == nil {
+			resolved := base.ResolveReference(ref)
+			if resolved.Scheme == "http" || resolved.Scheme == "https" {
+				p.ImageURL = resolved.String()
+			}
+		}
+	}
+
+	return p
+}
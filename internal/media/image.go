@@ -0,0 +1,189 @@
+package media
+
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+
+
+var ErrUnsupportedType = errors.New("unsupported media type")
+var ErrCorrupt = errors.New("media file is corrupt")
+
+
+// allowedTypes maps the sniffed content type to the extension files are
+// stored with.
+var allowedTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+
+// Info describes an uploaded file after it has been cleaned.
+type Info struct {
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+}
+
+
+
+// Process sniffs the content type of an upload, never trusting the one the
+// client sent, reads the image dimensions and strips EXIF metadata. It
+// returns the bytes that should be stored.
+func Process(data []byte) (Info, []byte, error) {
+
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return Info{}, nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Info{}, nil, ErrCorrupt
+	}
+
+	cleaned := data
+	switch contentType {
+	case "image/jpeg":
+		cleaned, err = StripJPEGMetadata(data)
+	case "image/png":
+		cleaned, err = StripPNGMetadata(data)
+	}
+	if err != nil {
+		return Info{}, nil, err
+	}
+
+	info := Info{
+		ContentType: contentType,
+		Extension:   ext,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+	}
+
+	return info, cleaned, nil
+}
+
+
+
+// StripJPEGMetadata drops APP1 (EXIF and XMP, including GPS coordinates),
+// APP13 (IPTC) and comments. APP2 and APP14 stay, they carry the ICC color
+// profile and the Adobe color transform the image needs to look right.
+// Everything from the start of scan marker on is image data and is copied as
+// is.
+func StripJPEGMetadata(data []byte) ([]byte, error) {
+
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrCorrupt
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+
+	i := 2
+	for i < len(data) {
+		if data[i] != 0xFF || i+1 >= len(data) {
+			return nil, ErrCorrupt
+		}
+		marker := data[i+1]
+
+		// fill bytes
+		if marker == 0xFF {
+			i++
+			continue
+		}
+
+		// start of scan or end of image, the rest is copied untouched
+		if marker == 0xDA || marker == 0xD9 {
+			out = append(out, data[i:]...)
+			return out, nil
+		}
+
+		// markers without a length
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+
+		if i+4 > len(data) {
+			return nil, ErrCorrupt
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrCorrupt
+		}
+
+		isMetadata := marker == 0xE1 || marker == 0xED || marker == 0xFE
+		if !isMetadata {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	return nil, ErrCorrupt
+}
+
+
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+
+// pngMetadataChunks are dropped from PNGs: eXIf holds EXIF data, and the
+// text chunks are where older tools put it.
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+}
+
+
+// StripPNGMetadata drops metadata chunks from a PNG, copying every other
+// chunk as is.
+func StripPNGMetadata(data []byte) ([]byte, error) {
+
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrCorrupt
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+
+	i := len(pngSignature)
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, ErrCorrupt
+		}
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		chunkType := string(data[i+4 : i+8])
+
+		// length, type, data and crc
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, ErrCorrupt
+		}
+
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+
+		if chunkType == "IEND" {
+			return out, nil
+		}
+	}
+
+	return nil, ErrCorrupt
+}
@@ -0,0 +1,269 @@
+package media
+
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+
+
+const gpsMarker = "GPSLatitude 35.0844 N"
+
+
+func testImage() image.Image {
+	return image.NewRGBA(image.Rect(0, 0, 40, 30))
+}
+
+
+// jpegWithExif encodes a JPEG and puts an APP1 segment right after SOI,
+// where cameras write it.
+func jpegWithExif(t *testing.T) []byte {
+	return jpegWithSegments(t, jpegSegment(0xE1, "Exif\x00\x00"+gpsMarker))
+}
+
+
+// jpegWithSegments encodes a JPEG with the given segments right after SOI.
+func jpegWithSegments(t *testing.T, segs ...[]byte) []byte {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, testImage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	out := append([]byte{}, data[:2]...)
+	for _, seg := range segs {
+		out = append(out, seg...)
+	}
+	return append(out, data[2:]...)
+}
+
+
+func jpegSegment(marker byte, payload string) []byte {
+	seg := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+
+// pngWithExif encodes a PNG and adds an eXIf chunk before IEND.
+func pngWithExif(t *testing.T) []byte {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, testImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	payload := []byte(gpsMarker)
+	chunk := make([]byte, 4)
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	chunk = append(chunk, "eXIf"...)
+	chunk = append(chunk, payload...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	chunk = append(chunk, crc...)
+
+	iend := len(data) - 12
+	out := append([]byte{}, data[:iend]...)
+	out = append(out, chunk...)
+	return append(out, data[iend:]...)
+}
+
+
+
+func TestProcess(t *testing.T) {
+
+	cases := []struct {
+		name        string
+		data        []byte
+		contentType string
+	}{
+		{"jpeg", jpegWithExif(t), "image/jpeg"},
+		{"png", pngWithExif(t), "image/png"},
+	}
+
+	for _, c := range cases {
+		if !bytes.Contains(c.data, []byte(gpsMarker)) {
+			t.Fatalf("%s: test image is missing its metadata", c.name)
+		}
+
+		info, cleaned, err := Process(c.data)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if info.ContentType != c.contentType {
+			t.Errorf("%s: content type %q, want %q", c.name, info.ContentType, c.contentType)
+		}
+		if info.Width != 40 || info.Height != 30 {
+			t.Errorf("%s: dimensions %dx%d, want 40x30", c.name, info.Width, info.Height)
+		}
+		if bytes.Contains(cleaned, []byte(gpsMarker)) {
+			t.Errorf("%s: metadata was not stripped", c.name)
+		}
+
+		// the cleaned file must still be a valid image
+		_, _, err = image.Decode(bytes.NewReader(cleaned))
+		if err != nil {
+			t.Errorf("%s: cleaned image doesn't decode: %v", c.name, err)
+		}
+	}
+}
+
+
+// cmykJPEG builds an 8x8 four component JPEG by hand, the standard library
+// can't encode one. Every coefficient is zero, so the scan is one byte: a one
+// bit DC code and a one bit end of block for each of the four blocks. Go only
+// decodes it with the Adobe segment, which segs must include.
+func cmykJPEG(segs ...[]byte) []byte {
+
+	out := []byte{0xFF, 0xD8}
+	for _, seg := range segs {
+		out = append(out, seg...)
+	}
+
+	// quantization table 0, all ones
+	out = append(out, jpegSegment(0xDB, "\x00"+strings.Repeat("\x01", 64))...)
+
+	// 8 bit, 8x8, four components without subsampling using table 0
+	out = append(out, jpegSegment(0xC0, "\x08\x00\x08\x00\x08\x04"+
+		"\x01\x11\x00\x02\x11\x00\x03\x11\x00\x04\x11\x00")...)
+
+	// DC and AC table 0 each hold a single one bit code for symbol 0
+	oneCode := "\x01" + strings.Repeat("\x00", 15) + "\x00"
+	out = append(out, jpegSegment(0xC4, "\x00"+oneCode+"\x10"+oneCode)...)
+
+	out = append(out, jpegSegment(0xDA, "\x04\x01\x00\x02\x00\x03\x00\x04\x00\x00\x3F\x00")...)
+	return append(out, 0x00, 0xFF, 0xD9)
+}
+
+
+
+func TestStripJPEGMetadata(t *testing.T) {
+
+	jfif := jpegSegment(0xE0, "JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")
+	icc := jpegSegment(0xE2, "ICC_PROFILE\x00\x01\x01"+"wide gamut profile")
+	data := jpegWithSegments(t,
+		jfif,
+		jpegSegment(0xE1, "http://ns.adobe.com/xap/1.0/\x00"+gpsMarker),
+		icc,
+		jpegSegment(0xED, "Photoshop 3.0\x00"+gpsMarker),
+		jpegSegment(0xFE, gpsMarker),
+	)
+
+	cleaned, err := StripJPEGMetadata(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(cleaned, []byte(gpsMarker)) {
+		t.Fatal("APP1, APP13 or COM segment was not stripped")
+	}
+	if !bytes.Equal(cleaned[2:2+len(jfif)+len(icc)], append(jfif, icc...)) {
+		t.Fatal("APP0 and the ICC profile in APP2 should be kept")
+	}
+
+	_, err = jpeg.Decode(bytes.NewReader(cleaned))
+	if err != nil {
+		t.Fatalf("cleaned image doesn't decode: %v", err)
+	}
+}
+
+
+func TestStripJPEGMetadataKeepsAdobeTransform(t *testing.T) {
+
+	// transform 0 marks the four components as plain CMYK
+	adobe := jpegSegment(0xEE, "Adobe\x00\x64\x00\x00\x00\x00\x00")
+	data := cmykJPEG(jpegSegment(0xE1, "Exif\x00\x00"+gpsMarker), adobe)
+
+	_, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("test image doesn't decode: %v", err)
+	}
+
+	cleaned, err := StripJPEGMetadata(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(cleaned, []byte(gpsMarker)) {
+		t.Fatal("APP1 segment was not stripped")
+	}
+	if !bytes.Contains(cleaned, adobe) {
+		t.Fatal("APP14 segment should be kept")
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(cleaned))
+	if err != nil {
+		t.Fatalf("cleaned image doesn't decode: %v", err)
+	}
+	if _, ok := img.(*image.CMYK); !ok {
+		t.Fatalf("cleaned image decodes as %T, want *image.CMYK", img)
+	}
+}
+
+
+func TestProcessRejectsUnsupported(t *testing.T) {
+
+	_, _, err := Process([]byte("<html><script>alert(1)</script></html>"))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("expected ErrUnsupportedType, got %v", err)
+	}
+
+	// a PNG signature followed by garbage sniffs as a PNG but isn't one
+	_, _, err = Process(append([]byte("\x89PNG\r\n\x1a\n"), "garbage"...))
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+}
+
+
+
+func TestFSStore(t *testing.T) {
+
+	ctx := context.Background()
+	s := NewFSStore(t.TempDir(), "/media/")
+
+	err := s.Put(ctx, "a.png", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := s.Open(ctx, "a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "hello" {
+		t.Fatalf("read %q, want %q", b, "hello")
+	}
+
+	if s.URL("a.png") != "/media/a.png" {
+		t.Fatalf("unexpected url %q", s.URL("a.png"))
+	}
+
+	err = s.Delete(ctx, "a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Delete(ctx, "a.png")
+	if err != nil {
+		t.Fatalf("deleting a missing blob should not fail: %v", err)
+	}
+
+	for _, key := range []string{"", "../a.png", "dir/a.png", ".hidden"} {
+		err = s.Put(ctx, key, strings.NewReader("x"))
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("key %q: expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package media
+
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+
+
+var ErrInvalidKey = errors.New("invalid blob key")
+
+
+// BlobStore is where uploaded files live. The filesystem store is the only
+// one for now, an S3 store only has to implement these four methods.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL is where clients can fetch the blob from
+	URL(key string) string
+}
+
+
+
+// FSStore keeps blobs as files under Dir. The server serves them under
+// BaseURL after checking who may see them.
+type FSStore struct {
+	Dir     string
+	BaseURL string
+}
+
+
+func NewFSStore(dir, baseURL string) *FSStore {
+	return &FSStore{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+
+func (s *FSStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.HasPrefix(key, ".") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.Dir, key), nil
+}
+
+
+// Put writes to a temporary file first so a half written upload is never
+// served.
+func (s *FSStore) Put(ctx context.Context, key string, r io.Reader) error {
+
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(s.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+
+func (s *FSStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+
+// Delete removes the blob, deleting one that is already gone is not an error.
+func (s *FSStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+
+func (s *FSStore) URL(key string) string {
+	return s.BaseURL + "/" + key
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+
+// Limit describes a token bucket. Rate tokens are added every second, up to
+// Burst tokens in total. A request costs one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+
+// Result is the outcome of a single Allow call, carrying everything needed to
+// fill in the X-RateLimit-* and Retry-After response headers.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+
+func New() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+
+// Allow takes one token from the bucket identified by key, creating a full
+// bucket on first use.
+func (l *Limiter) Allow(key string, limit Limit) Result {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	burst := float64(limit.Burst)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	// refill based on the time since the bucket was last touched
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*limit.Rate)
+	}
+	b.last = now
+
+	res := Result{Limit: limit.Burst}
+
+	if b.tokens >= 1 {
+		b.tokens -= 1
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+
+	res.Remaining = int(math.Floor(b.tokens))
+	res.Reset = secondsToDuration((burst - b.tokens) / limit.Rate)
+
+	return res
+}
+
+
+// Cleanup drops buckets that have been idle long enough to refill completely,
+// since they are indistinguishable from a brand new bucket.
+func (l *Limiter) Cleanup(maxIdle time.Duration) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > maxIdle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+
+// Run calls Cleanup every interval until ctx is cancelled.
+func (l *Limiter) Run(ctx context.Context, interval, maxIdle time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.Cleanup(maxIdle)
+		}
+	}
+}
+
+
+func secondsToDuration(s float64) time.Duration {
+	if s <= 0 || math.IsInf(s, 0) || math.IsNaN(s) {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+
+import (
+	"testing"
+	"time"
+)
+
+
+
+
+func TestAllowExhaustsBurst(t *testing.T) {
+
+	l := New()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 1, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		res := l.Allow("ip:1.2.3.4", limit)
+		if !res.Allowed {
+			t.Fatalf("request %d should have been allowed", i)
+		}
+		if res.Remaining != 2-i {
+			t.Fatalf("expected %d remaining, got %d", 2-i, res.Remaining)
+		}
+	}
+
+	res := l.Allow("ip:1.2.3.4", limit)
+	if res.Allowed {
+		t.Fatalf("4th request should have been limited")
+	}
+	if res.RetryAfter != time.Second {
+		t.Fatalf("expected retry after 1s, got %v", res.RetryAfter)
+	}
+
+	// other keys have their own bucket
+	if !l.Allow("ip:5.6.7.8", limit).Allowed {
+		t.Fatalf("separate key should not be limited")
+	}
+}
+
+
+
+func TestAllowRefills(t *testing.T) {
+
+	l := New()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 2, Burst: 1}
+
+	if !l.Allow("k", limit).Allowed {
+		t.Fatalf("first request should be allowed")
+	}
+	if l.Allow("k", limit).Allowed {
+		t.Fatalf("second request should be limited")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+
+	if !l.Allow("k", limit).Allowed {
+		t.Fatalf("bucket should have refilled after 500ms")
+	}
+}
+
+
+
+func TestCleanup(t *testing.T) {
+
+	l := New()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	l.Allow("k", Limit{Rate: 1, Burst: 1})
+	now = now.Add(time.Hour)
+	l.Cleanup(time.Minute)
+
+	if len(l.buckets) != 0 {
+		t.Fatalf("expected idle bucket to be removed")
+	}
+}
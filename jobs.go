@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+)
+
+
+
+// runPurgeDeletedChirps hard deletes soft deleted chirps once they are older
+// than the retention period. It runs every interval until ctx is cancelled.
+func (cfg *apiConfig) runPurgeDeletedChirps(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := cfg.DBQueries.PurgeDeletedChirps(ctx, time.Now().UTC().Add(-cfg.chirpRetention))
+		if err != nil {
+			log.Printf("purge deleted chirps: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d deleted chirps", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+
+
+// runExportJobs works through queued data exports one at a time. Jobs are
+// claimed with SKIP LOCKED, so several instances can share the queue. A job
+// still running after exportJobTimeout is given to the next worker.
+func (cfg *apiConfig) runExportJobs(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			job, err := cfg.DBQueries.ClaimExportJob(ctx, time.Now().UTC().Add(-cfg.exportJobTimeout))
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				log.Printf("claim export job: %v", err)
+				break
+			}
+
+			jobCtx, cancel := context.WithTimeout(ctx, cfg.exportJobTimeout)
+			err = cfg.runExportJob(jobCtx, job)
+			cancel()
+			if err != nil {
+				log.Printf("export job %s: %v", job.ID, err)
+				// ctx may be what failed the job
+				cfg.DBQueries.FailExportJob(context.Background(), database.FailExportJobParams {
+					ID:    job.ID,
+					Error: "export failed",
+				})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+
+
+// runPurgeAccounts deletes accounts whose deletion grace period is over,
+// along with expired export archives. Chirps, refresh tokens, export jobs and
+// media go with the user through ON DELETE CASCADE, their files are removed
+// here first.
+func (cfg *apiConfig) runPurgeAccounts(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// the export rows cascade, but their files have to be removed by hand
+		paths, err := cfg.DBQueries.ListExportFilesOfUsersDueForPurge(ctx)
+		if err != nil {
+			log.Printf("list exports of deleted users: %v", err)
+		}
+		for _, path := range paths {
+			os.Remove(path)
+		}
+
+		// same for uploads, the files would otherwise outlive the account
+		if err == nil {
+			var keys []string
+			keys, err = cfg.DBQueries.ListMediaOfUsersDueForPurge(ctx)
+			if err != nil {
+				log.Printf("list media of deleted users: %v", err)
+			}
+			for _, key := range keys {
+				derr := cfg.mediaStore.Delete(ctx, key)
+				if derr != nil {
+					log.Printf("delete media %s: %v", key, derr)
+				}
+			}
+		}
+
+		if err == nil {
+			n, err := cfg.DBQueries.PurgeDeletedUsers(ctx)
+			if err != nil {
+				log.Printf("purge deleted users: %v", err)
+			} else if n > 0 {
+				log.Printf("purged %d deleted accounts", n)
+			}
+		}
+
+		jobs, err := cfg.DBQueries.ListExpiredExportJobs(ctx, time.Now().UTC().Add(-cfg.exportRetention))
+		if err != nil {
+			log.Printf("list expired exports: %v", err)
+		}
+		for _, job := range jobs {
+			if job.FilePath != "" {
+				os.Remove(job.FilePath)
+			}
+			cfg.DBQueries.DeleteExportJob(ctx, job.ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+
+
+// runPurgeMedia removes uploads that never made it into a chirp, and those
+// of chirps that have since been purged, along with their files.
+func (cfg *apiConfig) runPurgeMedia(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		keys, err := cfg.DBQueries.PurgeUnattachedMedia(ctx, time.Now().UTC().Add(-cfg.mediaUnattachedTTL))
+		if err != nil {
+			log.Printf("purge unattached media: %v", err)
+		}
+
+		for _, key := range keys {
+			err = cfg.mediaStore.Delete(ctx, key)
+			if err != nil {
+				log.Printf("delete media %s: %v", key, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+
+
+// runLinkPreviews fetches queued link previews until ctx is cancelled.
+// Several of these run side by side so one slow site doesn't hold up the
+// rest.
+func (cfg *apiConfig) runLinkPreviews(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case u := <-cfg.previewQueue:
+			fetchCtx, cancel := context.WithTimeout(ctx, 2*cfg.previewFetcher.Timeout)
+			cfg.fetchLinkPreview(fetchCtx, u)
+			cancel()
+		}
+	}
+}
+
+
+
+// runPublishScheduledChirps publishes chirps whose publish_at has passed.
+// Each one is claimed with SKIP LOCKED, so several instances can run this
+// without publishing anything twice.
+func (cfg *apiConfig) runPublishScheduledChirps(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			chirp, err := cfg.DBQueries.PublishDueChirp(ctx)
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				log.Printf("publish scheduled chirp: %v", err)
+				break
+			}
+			cfg.chirpPublished(chirp)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -11,16 +11,81 @@ import (
 	"os"
 	"github.com/DylanCoon99/bootdev-server/internal/database"
 	"database/sql"
+	"github.com/DylanCoon99/bootdev-server/internal/ratelimit"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/media"
+	"github.com/DylanCoon99/bootdev-server/internal/links"
+	"github.com/DylanCoon99/bootdev-server/internal/events"
+	"context"
+	"strconv"
+	"strings"
+	"time"
 )
 
 
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	DB        *sql.DB
 	DBQueries *database.Queries
 	Platform  string
 	jwtSecret string
 	polkaKey  string
+	limiter    *ratelimit.Limiter
+	rateLimits rateLimitConfig
+	reportHideThreshold int
+	chirpUndoWindow     time.Duration
+	chirpRetention      time.Duration
+	accountDeletionGrace time.Duration
+	exportDir            string
+	exportSyncMaxChirps  int
+	exportRetention      time.Duration
+	exportJobTimeout     time.Duration
+	mediaStore           media.BlobStore
+	mediaMaxBytes        int64
+	mediaUnattachedTTL   time.Duration
+	previewFetcher       *links.Fetcher
+	previewQueue         chan string
+	linkPreviewTTL       time.Duration
+	// where the server is reachable from outside, short links point here
+	publicURL            string
+	events               events.Bus
+	streamBuffer         int
+	streamHeartbeat      time.Duration
+	wsConnections        *wsConnections
+	wsMaxPerUser         int
+	maxPins              int
+	maxPinsRed           int
+}
+
+
+
+// envInt reads a whole number from the environment.
+func envInt(name string, def int) int {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		log.Fatalf("%s must be a number", name)
+	}
+	return n
+}
+
+
+
+// envDuration reads a duration such as "5m" or "720h" from the environment.
+func envDuration(name string, def time.Duration) time.Duration {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Fatalf("%s must be a duration like 5m or 720h", name)
+	}
+	return d
 }
 
 
@@ -32,9 +97,18 @@ func main() {
 	dbURL := os.Getenv("DB_URL")
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
-		fmt.Errorf("Failed to start db")
+		log.Println("Failed to start db")
 		return
 	}
+
+	if len(os.Args) > 1 {
+		err = runCommand(database.New(db), os.Args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	jwtSecret := os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
 		log.Fatal("JWT_SECRET environment variable is not set")
@@ -52,28 +126,213 @@ func main() {
 
 
 	var apiCfg apiConfig
+	apiCfg.DB = db
 	apiCfg.DBQueries = dbQueries
+	// chirps are hidden automatically once they have this many open reports,
+	// 0 turns auto-hiding off
+	apiCfg.reportHideThreshold = envInt("REPORT_HIDE_THRESHOLD", 5)
+	apiCfg.chirpUndoWindow = envDuration("CHIRP_UNDO_WINDOW", 5*time.Minute)
+	apiCfg.chirpRetention = envDuration("CHIRP_RETENTION", 30*24*time.Hour)
+	apiCfg.accountDeletionGrace = envDuration("ACCOUNT_DELETION_GRACE", 14*24*time.Hour)
+	apiCfg.exportSyncMaxChirps = envInt("EXPORT_SYNC_MAX_CHIRPS", 1000)
+	apiCfg.exportRetention = envDuration("EXPORT_RETENTION", 7*24*time.Hour)
+	apiCfg.exportJobTimeout = envDuration("EXPORT_JOB_TIMEOUT", 30*time.Minute)
+	apiCfg.exportDir = os.Getenv("EXPORT_DIR")
+	if apiCfg.exportDir == "" {
+		apiCfg.exportDir = "exports"
+	}
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	apiCfg.mediaStore = media.NewFSStore(mediaDir, "/media/")
+	apiCfg.mediaMaxBytes = int64(envInt("MEDIA_MAX_BYTES", 5<<20))
+	apiCfg.mediaUnattachedTTL = envDuration("MEDIA_UNATTACHED_TTL", 24*time.Hour)
+	apiCfg.previewFetcher = links.NewFetcher()
+	apiCfg.previewQueue = make(chan string, 256)
+	apiCfg.linkPreviewTTL = envDuration("LINK_PREVIEW_TTL", 24*time.Hour)
+	apiCfg.publicURL = strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if apiCfg.publicURL == "" {
+		apiCfg.publicURL = "http://localhost:8080"
+	}
+	// live clients that fall more than streamBuffer events behind are
+	// disconnected and catch up from the replay buffer when they reconnect
+	// with EVENT_BUS=postgres, events reach clients of every instance
+	replaySize := envInt("EVENT_REPLAY_SIZE", 1000)
+	switch os.Getenv("EVENT_BUS") {
+	case "", "memory":
+		apiCfg.events = events.NewMemory(replaySize)
+	case "postgres":
+		bus, err := events.NewPostgres(db, dbURL, "chirpy_events", replaySize)
+		if err != nil {
+			log.Fatalf("couldn't listen for events: %v", err)
+		}
+		defer bus.Close()
+		apiCfg.events = bus
+	default:
+		log.Fatal("EVENT_BUS must be memory or postgres")
+	}
+	apiCfg.streamBuffer = envInt("STREAM_BUFFER", 64)
+	apiCfg.streamHeartbeat = envDuration("STREAM_HEARTBEAT", 15*time.Second)
+	apiCfg.wsConnections = newWSConnections()
+	apiCfg.wsMaxPerUser = envInt("WS_MAX_CONNECTIONS_PER_USER", 5)
+	apiCfg.maxPins = envInt("MAX_PINNED_CHIRPS", 1)
+	apiCfg.maxPinsRed = envInt("MAX_PINNED_CHIRPS_RED", 5)
 	apiCfg.Platform = platform
 	apiCfg.polkaKey = polkaKey
+	apiCfg.jwtSecret = jwtSecret
+
+	// token bucket limits: Rate is requests per second, Burst is the bucket size
+	apiCfg.limiter = ratelimit.New()
+	apiCfg.rateLimits = rateLimitConfig{
+		Global:  ratelimit.Limit{Rate: 20, Burst: 60},
+		Default: routeLimit{
+			Default: ratelimit.Limit{Rate: 5, Burst: 20},
+			Red:     ratelimit.Limit{Rate: 10, Burst: 40},
+		},
+		Routes: map[string]routeLimit{
+			"POST /api/chirps": {
+				Default: ratelimit.Limit{Rate: 1.0 / 30, Burst: 5},
+				Red:     ratelimit.Limit{Rate: 1.0 / 10, Burst: 15},
+			},
+			"POST /api/login": {
+				Default: ratelimit.Limit{Rate: 1.0 / 60, Burst: 5},
+				Red:     ratelimit.Limit{Rate: 1.0 / 60, Burst: 5},
+			},
+			"POST /api/chirps/{chirpID}/report": {
+				Default: ratelimit.Limit{Rate: 1.0 / 60, Burst: 10},
+				Red:     ratelimit.Limit{Rate: 1.0 / 60, Burst: 10},
+			},
+			// publishing a draft creates a chirp, so it gets the same limit
+			"POST /api/drafts/{draftID}/publish": {
+				Default: ratelimit.Limit{Rate: 1.0 / 30, Burst: 5},
+				Red:     ratelimit.Limit{Rate: 1.0 / 10, Burst: 15},
+			},
+			"POST /api/chirps/{chirpID}/rechirp": {
+				Default: ratelimit.Limit{Rate: 1.0 / 10, Burst: 20},
+				Red:     ratelimit.Limit{Rate: 1.0 / 5, Burst: 40},
+			},
+			"POST /api/media": {
+				Default: ratelimit.Limit{Rate: 1.0 / 30, Burst: 10},
+				Red:     ratelimit.Limit{Rate: 1.0 / 10, Burst: 20},
+			},
+			"POST /api/conversations": {
+				Default: ratelimit.Limit{Rate: 1.0 / 60, Burst: 10},
+				Red:     ratelimit.Limit{Rate: 1.0 / 30, Burst: 20},
+			},
+			"POST /api/users": {
+				Default: ratelimit.Limit{Rate: 1.0 / 300, Burst: 3},
+				Red:     ratelimit.Limit{Rate: 1.0 / 300, Burst: 3},
+			},
+		},
+	}
+
+	go apiCfg.limiter.Run(context.Background(), time.Minute, time.Hour)
+	go apiCfg.runPurgeDeletedChirps(context.Background(), time.Hour)
+	go apiCfg.runPurgeAccounts(context.Background(), time.Hour)
+	go apiCfg.runExportJobs(context.Background(), 10*time.Second)
+	go apiCfg.runPurgeMedia(context.Background(), time.Hour)
+	go apiCfg.runPublishScheduledChirps(context.Background(), 5*time.Second)
+	for i := 0; i < envInt("LINK_PREVIEW_WORKERS", 4); i++ {
+		go apiCfg.runLinkPreviews(context.Background())
+	}
 
 	// create a new http.ServeMux
 	serveMultiplexer := http.NewServeMux()
 
 	serveMultiplexer.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
+	serveMultiplexer.HandleFunc("GET /media/{key}", apiCfg.optionalAuth(apiCfg.handlerServeMedia))
 	serveMultiplexer.HandleFunc("GET /api/healthz", healthHandler)
-	serveMultiplexer.HandleFunc("GET /admin/metrics", apiCfg.hitsHandler)
-	serveMultiplexer.HandleFunc("POST /admin/reset", apiCfg.resetMetricsHandler)
+	serveMultiplexer.HandleFunc("GET /admin/metrics", apiCfg.requireRole(auth.RoleAdmin, apiCfg.hitsHandler))
+	serveMultiplexer.HandleFunc("POST /admin/reset", apiCfg.requireRole(auth.RoleAdmin, apiCfg.resetMetricsHandler))
+	serveMultiplexer.HandleFunc("PUT /admin/users/{userID}/role", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handlerSetUserRole))
+	serveMultiplexer.HandleFunc("POST /admin/users/{userID}/suspension", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handlerSuspendUser))
+	serveMultiplexer.HandleFunc("DELETE /admin/users/{userID}/suspension", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handlerUnsuspendUser))
+	serveMultiplexer.HandleFunc("POST /admin/users/{userID}/ban", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handlerBanUser))
+	serveMultiplexer.HandleFunc("DELETE /admin/users/{userID}/ban", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handlerUnbanUser))
 	//serveMultiplexer.HandleFunc("POST /api/validate_chirp", validateChirpHandler)
 	serveMultiplexer.HandleFunc("POST /api/users", apiCfg.createUserHandler)
-	serveMultiplexer.HandleFunc("POST /api/chirps", apiCfg.createChirpHandler)
-	serveMultiplexer.HandleFunc("GET /api/chirps", apiCfg.getChirpsHandler)
-	serveMultiplexer.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirpHandler)
+	serveMultiplexer.HandleFunc("POST /api/chirps", apiCfg.requireAuth(apiCfg.createChirpHandler))
+	serveMultiplexer.HandleFunc("POST /api/media", apiCfg.requireAuth(apiCfg.handlerUploadMedia))
+	serveMultiplexer.HandleFunc("GET /l/{code}", apiCfg.handlerFollowShortLink)
+	serveMultiplexer.HandleFunc("GET /api/chirps", apiCfg.optionalAuth(apiCfg.getChirpsHandler))
+	serveMultiplexer.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.optionalAuth(apiCfg.getChirpHandler))
 	serveMultiplexer.HandleFunc("POST /api/login", apiCfg.loginHandler)
 	serveMultiplexer.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
 	serveMultiplexer.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
-	serveMultiplexer.HandleFunc("PUT /api/users", apiCfg.handlerUpdateUser)
-	serveMultiplexer.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
+	serveMultiplexer.HandleFunc("PUT /api/users", apiCfg.requireAuth(apiCfg.handlerUpdateUser))
+	serveMultiplexer.HandleFunc("DELETE /api/users/me", apiCfg.requireAuth(apiCfg.handlerDeleteAccount))
+	serveMultiplexer.HandleFunc("PUT /api/users/me/profile", apiCfg.requireAuth(apiCfg.handlerUpdateProfile))
+	serveMultiplexer.HandleFunc("GET /api/users/{handle}", apiCfg.handlerGetProfile)
+	serveMultiplexer.HandleFunc("GET /api/users/me/export", apiCfg.requireAuth(apiCfg.handlerExportAccount))
+	serveMultiplexer.HandleFunc("GET /api/users/me/exports/{exportID}", apiCfg.requireAuth(apiCfg.handlerGetExportJob))
+	serveMultiplexer.HandleFunc("GET /api/users/me/exports/{exportID}/download", apiCfg.requireAuth(apiCfg.handlerDownloadExport))
+	serveMultiplexer.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.requireAuth(apiCfg.handlerDeleteChirp))
+	serveMultiplexer.HandleFunc("POST /api/chirps/{chirpID}/restore", apiCfg.requireAuth(apiCfg.handlerRestoreChirp))
+	serveMultiplexer.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.requireAuth(apiCfg.handlerRechirp))
+	serveMultiplexer.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.requireAuth(apiCfg.handlerUndoRechirp))
+	serveMultiplexer.HandleFunc("GET /api/chirps/scheduled", apiCfg.requireAuth(apiCfg.handlerListScheduledChirps))
+	serveMultiplexer.HandleFunc("GET /api/chirps/stream", apiCfg.optionalAuth(apiCfg.handlerChirpStream))
+	serveMultiplexer.HandleFunc("GET /api/ws", apiCfg.handlerWebSocket)
+	serveMultiplexer.HandleFunc("GET /api/notifications", apiCfg.requireAuth(apiCfg.handlerListNotifications))
+	serveMultiplexer.HandleFunc("GET /api/notifications/unread_count", apiCfg.requireAuth(apiCfg.handlerUnreadNotificationCount))
+	serveMultiplexer.HandleFunc("POST /api/notifications/{notificationID}/read", apiCfg.requireAuth(apiCfg.handlerMarkNotificationRead))
+	serveMultiplexer.HandleFunc("POST /api/notifications/read_all", apiCfg.requireAuth(apiCfg.handlerMarkAllNotificationsRead))
+	serveMultiplexer.HandleFunc("GET /api/notifications/preferences", apiCfg.requireAuth(apiCfg.handlerGetNotificationPreferences))
+	serveMultiplexer.HandleFunc("PUT /api/notifications/preferences", apiCfg.requireAuth(apiCfg.handlerUpdateNotificationPreferences))
+
+	serveMultiplexer.HandleFunc("POST /api/conversations", apiCfg.requireAuth(apiCfg.handlerCreateConversation))
+	serveMultiplexer.HandleFunc("GET /api/conversations", apiCfg.requireAuth(apiCfg.handlerListConversations))
+	serveMultiplexer.HandleFunc("GET /api/conversations/{conversationID}", apiCfg.requireAuth(apiCfg.handlerGetConversation))
+	serveMultiplexer.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.requireAuth(apiCfg.handlerSendMessage))
+	serveMultiplexer.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.requireAuth(apiCfg.handlerListMessages))
+	serveMultiplexer.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.requireAuth(apiCfg.handlerMarkConversationRead))
+	serveMultiplexer.HandleFunc("POST /api/users/{userID}/message_block", apiCfg.requireAuth(apiCfg.handlerBlockMessages))
+	serveMultiplexer.HandleFunc("DELETE /api/users/{userID}/message_block", apiCfg.requireAuth(apiCfg.handlerUnblockMessages))
+	serveMultiplexer.HandleFunc("POST /api/chirps/{chirpID}/pin", apiCfg.requireAuth(apiCfg.handlerPinChirp))
+	serveMultiplexer.HandleFunc("DELETE /api/chirps/{chirpID}/pin", apiCfg.requireAuth(apiCfg.handlerUnpinChirp))
+	serveMultiplexer.HandleFunc("POST /api/chirps/{chirpID}/vote", apiCfg.requireAuth(apiCfg.handlerVote))
+	serveMultiplexer.HandleFunc("POST /api/chirps/{chirpID}/bookmark", apiCfg.requireAuth(apiCfg.handlerBookmarkChirp))
+	serveMultiplexer.HandleFunc("PUT /api/chirps/{chirpID}/bookmark", apiCfg.requireAuth(apiCfg.handlerMoveBookmark))
+	serveMultiplexer.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.requireAuth(apiCfg.handlerDeleteBookmark))
+	serveMultiplexer.HandleFunc("GET /api/bookmarks", apiCfg.requireAuth(apiCfg.handlerListBookmarks))
+	serveMultiplexer.HandleFunc("POST /api/bookmarks/collections", apiCfg.requireAuth(apiCfg.handlerCreateCollection))
+	serveMultiplexer.HandleFunc("GET /api/bookmarks/collections", apiCfg.requireAuth(apiCfg.handlerListCollections))
+	serveMultiplexer.HandleFunc("PUT /api/bookmarks/collections/{collectionID}", apiCfg.requireAuth(apiCfg.handlerRenameCollection))
+	serveMultiplexer.HandleFunc("DELETE /api/bookmarks/collections/{collectionID}", apiCfg.requireAuth(apiCfg.handlerDeleteCollection))
+	serveMultiplexer.HandleFunc("POST /api/lists", apiCfg.requireAuth(apiCfg.handlerCreateList))
+	serveMultiplexer.HandleFunc("GET /api/lists", apiCfg.requireAuth(apiCfg.handlerListLists))
+	serveMultiplexer.HandleFunc("GET /api/lists/{listID}", apiCfg.optionalAuth(apiCfg.handlerGetList))
+	serveMultiplexer.HandleFunc("PUT /api/lists/{listID}", apiCfg.requireAuth(apiCfg.handlerUpdateList))
+	serveMultiplexer.HandleFunc("DELETE /api/lists/{listID}", apiCfg.requireAuth(apiCfg.handlerDeleteList))
+	serveMultiplexer.HandleFunc("GET /api/lists/{listID}/members", apiCfg.optionalAuth(apiCfg.handlerListListMembers))
+	serveMultiplexer.HandleFunc("POST /api/lists/{listID}/members/{userID}", apiCfg.requireAuth(apiCfg.handlerAddListMember))
+	serveMultiplexer.HandleFunc("DELETE /api/lists/{listID}/members/{userID}", apiCfg.requireAuth(apiCfg.handlerRemoveListMember))
+	serveMultiplexer.HandleFunc("GET /api/lists/{listID}/chirps", apiCfg.optionalAuth(apiCfg.handlerListListChirps))
+	serveMultiplexer.HandleFunc("POST /api/lists/{listID}/subscription", apiCfg.requireAuth(apiCfg.handlerSubscribeToList))
+	serveMultiplexer.HandleFunc("DELETE /api/lists/{listID}/subscription", apiCfg.requireAuth(apiCfg.handlerUnsubscribeFromList))
+	serveMultiplexer.HandleFunc("POST /api/users/{userID}/block", apiCfg.requireAuth(apiCfg.handlerBlockUser))
+	serveMultiplexer.HandleFunc("DELETE /api/users/{userID}/block", apiCfg.requireAuth(apiCfg.handlerUnblockUser))
+	serveMultiplexer.HandleFunc("POST /api/users/{userID}/mute", apiCfg.requireAuth(apiCfg.handlerMuteUser))
+	serveMultiplexer.HandleFunc("DELETE /api/users/{userID}/mute", apiCfg.requireAuth(apiCfg.handlerUnmuteUser))
+	serveMultiplexer.HandleFunc("POST /api/drafts", apiCfg.requireAuth(apiCfg.handlerCreateDraft))
+	serveMultiplexer.HandleFunc("GET /api/drafts", apiCfg.requireAuth(apiCfg.handlerListDrafts))
+	serveMultiplexer.HandleFunc("GET /api/drafts/{draftID}", apiCfg.requireAuth(apiCfg.handlerGetDraft))
+	serveMultiplexer.HandleFunc("PUT /api/drafts/{draftID}", apiCfg.requireAuth(apiCfg.handlerUpdateDraft))
+	serveMultiplexer.HandleFunc("DELETE /api/drafts/{draftID}", apiCfg.requireAuth(apiCfg.handlerDeleteDraft))
+	serveMultiplexer.HandleFunc("POST /api/drafts/{draftID}/publish", apiCfg.requireAuth(apiCfg.handlerPublishDraft))
+	serveMultiplexer.HandleFunc("PUT /api/chirps/{chirpID}/schedule", apiCfg.requireAuth(apiCfg.handlerRescheduleChirp))
+	serveMultiplexer.HandleFunc("DELETE /api/chirps/{chirpID}/schedule", apiCfg.requireAuth(apiCfg.handlerCancelScheduledChirp))
+
 	serveMultiplexer.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerWebhook)
+	serveMultiplexer.HandleFunc("POST /api/chirps/{chirpID}/report", apiCfg.requireAuth(apiCfg.handlerReportChirp))
+	serveMultiplexer.HandleFunc("GET /api/moderation/reports", apiCfg.requireRole(auth.RoleModerator, apiCfg.handlerListReports))
+	serveMultiplexer.HandleFunc("POST /api/moderation/reports/{reportID}/dismiss", apiCfg.requireRole(auth.RoleModerator, apiCfg.handlerDismissReport))
+	serveMultiplexer.HandleFunc("POST /api/moderation/chirps/{chirpID}/hide", apiCfg.requireRole(auth.RoleModerator, apiCfg.handlerModerateHideChirp))
+	serveMultiplexer.HandleFunc("POST /api/moderation/chirps/{chirpID}/restore", apiCfg.requireRole(auth.RoleModerator, apiCfg.handlerModerateRestoreChirp))
+	serveMultiplexer.HandleFunc("GET /api/moderation/actions", apiCfg.requireRole(auth.RoleModerator, apiCfg.handlerListModerationActions))
+	serveMultiplexer.HandleFunc("GET /api/moderation/chirps/{chirpID}", apiCfg.requireRole(auth.RoleModerator, apiCfg.handlerModerationGetChirp))
+	serveMultiplexer.HandleFunc("GET /api/moderation/users/{userID}/chirps", apiCfg.requireRole(auth.RoleModerator, apiCfg.handlerModerationListUserChirps))
 
 
 
@@ -81,7 +340,7 @@ func main() {
 
 	server := &http.Server{
 		Addr: ":" + port,
-		Handler: serveMultiplexer,
+		Handler: apiCfg.middlewareRateLimit(serveMultiplexer),
 	}
 
 	err = server.ListenAndServe()
@@ -89,7 +348,7 @@ func main() {
 	fmt.Printf("Serving on port: %s", port)
 
 	if err != nil {
-		fmt.Errorf("Failed to listen and serve")
+		log.Println("Failed to listen and serve")
 		return
 	}
 
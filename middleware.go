@@ -1,11 +1,53 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/ratelimit"
+	"github.com/google/uuid"
 )
 
 
 
+type contextKey string
+
+const principalKey contextKey = "principal"
+
+
+// principal is the authenticated caller of a request, stored in the request
+// context by middlewareAuth.
+type principal struct {
+	UserID uuid.UUID
+	Claims auth.Claims
+}
+
+
+var errNoToken = errors.New("no bearer token")
+
+
+
+// routeLimit is the rate limit applied to a single route. Chirpy Red users get
+// the Red limit, everyone else gets Default.
+type routeLimit struct {
+	Default ratelimit.Limit
+	Red     ratelimit.Limit
+}
+
+
+type rateLimitConfig struct {
+	// Global applies per client IP across every route
+	Global  ratelimit.Limit
+	// Default applies to routes without an entry in Routes
+	Default routeLimit
+	// Routes is keyed by the ServeMux pattern, e.g. "POST /api/chirps"
+	Routes  map[string]routeLimit
+}
+
 
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -14,4 +56,192 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 		cfg.fileserverHits.Add(1)
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+
+
+// middlewareRateLimit wraps the whole mux. Every request is charged against a
+// per-IP global bucket, then against a bucket for the matched route keyed by
+// the authenticated user (or the IP for anonymous requests).
+func (cfg *apiConfig) middlewareRateLimit(mux *http.ServeMux) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		ip := clientIP(r)
+
+		res := cfg.limiter.Allow("ip:" + ip, cfg.rateLimits.Global)
+		if !res.Allowed {
+			writeRateLimited(w, res)
+			return
+		}
+
+		_, pattern := mux.Handler(r)
+		if pattern == "" {
+			// no route matched, let the mux write the 404/405
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		limit, ok := cfg.rateLimits.Routes[pattern]
+		if !ok {
+			limit = cfg.rateLimits.Default
+		}
+
+		subject := "ip:" + ip
+		chosen := limit.Default
+
+		// this runs before routing, so it can't rely on middlewareAuth
+		p, err := cfg.authenticate(r)
+		if err == nil {
+			subject = "user:" + p.UserID.String()
+
+			// only hit the database when the route treats Chirpy Red differently
+			if limit.Red != limit.Default {
+				user, err := cfg.DBQueries.GetUserByID(r.Context(), p.UserID)
+				if err == nil && user.IsChirpyRed {
+					chosen = limit.Red
+				}
+			}
+		}
+
+		res = cfg.limiter.Allow(pattern + "|" + subject, chosen)
+		setRateLimitHeaders(w, res)
+		if !res.Allowed {
+			writeRateLimited(w, res)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+
+
+// authenticate validates the bearer JWT on the request. It returns errNoToken
+// when the request carries no Authorization header at all.
+func (cfg *apiConfig) authenticate(r *http.Request) (principal, error) {
+
+	if r.Header.Get("Authorization") == "" {
+		return principal{}, errNoToken
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return principal{}, err
+	}
+
+	claims, err := auth.ParseJWT(token, cfg.jwtSecret)
+	if err != nil {
+		return principal{}, err
+	}
+
+	return principal{UserID: claims.UserID, Claims: claims}, nil
+}
+
+
+
+// middlewareAuth authenticates the request once and stores the principal in
+// the request context. With required set, anonymous requests are rejected;
+// otherwise they pass through without a principal. A token that is present
+// but invalid is always rejected.
+func (cfg *apiConfig) middlewareAuth(required bool, next http.HandlerFunc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		p, err := cfg.authenticate(r)
+		if errors.Is(err, errNoToken) && !required {
+			next(w, r)
+			return
+		}
+		if errors.Is(err, errNoToken) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("Couldn't find JWT"))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("Couldn't validate JWT"))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), principalKey, p)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+
+func (cfg *apiConfig) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return cfg.middlewareAuth(true, next)
+}
+
+
+func (cfg *apiConfig) optionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return cfg.middlewareAuth(false, next)
+}
+
+
+// requireRole only lets through callers who currently hold at least the
+// given role. The role in the JWT is not trusted here, the user is loaded so
+// a demoted, suspended or banned staff member loses access right away.
+func (cfg *apiConfig) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+
+	return cfg.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+
+		caller, _ := principalFromContext(r.Context())
+
+		user, err := cfg.DBQueries.GetUserByID(r.Context(), caller.UserID)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("User not found"))
+			return
+		}
+
+		if !auth.HasRole(user.Role, role) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Insufficient role"))
+			return
+		}
+
+		if restriction := accountRestriction(user); restriction != "" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(restriction))
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+
+// principalFromContext returns the caller set by middlewareAuth. ok is false
+// for anonymous requests on optionalAuth routes.
+func principalFromContext(ctx context.Context) (p principal, ok bool) {
+	p, ok = ctx.Value(principalKey).(principal)
+	return p, ok
+}
+
+
+
+func clientIP(r *http.Request) string {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+
+func setRateLimitHeaders(w http.ResponseWriter, res ratelimit.Result) {
+	w.Header().Set("X-RateLimit-Limit", fmt.Sprint(res.Limit))
+	w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(res.Remaining))
+	w.Header().Set("X-RateLimit-Reset", fmt.Sprint(int(math.Ceil(res.Reset.Seconds()))))
+}
+
+
+func writeRateLimited(w http.ResponseWriter, res ratelimit.Result) {
+	setRateLimitHeaders(w, res)
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(res.RetryAfter.Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write([]byte("Too many requests"))
+}
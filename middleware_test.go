@@ -0,0 +1,50 @@
+package main
+
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+)
+
+
+
+func TestRequireRoleUsesTheStoredRole(t *testing.T) {
+
+	// the token still says admin but the fake database has the caller as a
+	// plain user, as if they were demoted after logging in
+	cfg, _ := newFakeConfig(t, nil)
+
+	token, err := auth.MakeJWT(fakeUserID, auth.RoleAdmin, "secret", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	called := false
+	handler := cfg.requireRole(auth.RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	r := httptest.NewRequest("GET", "/admin/metrics", nil)
+	r.Header.Set("Authorization", "Bearer " + token)
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	if w.Code != http.StatusForbidden || called {
+		t.Fatalf("want 403 for a demoted admin, got %d (handler called: %v)", w.Code, called)
+	}
+
+	// a user role is enough for a user route, whatever the token says
+	handler = cfg.requireRole(auth.RoleUser, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	w = httptest.NewRecorder()
+	handler(w, r)
+
+	if !called {
+		t.Fatalf("want the handler called, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+
+
+// parseLimit reads the optional ?limit= query parameter.
+func parseLimit(r *http.Request, def, max int32) (int32, error) {
+
+	s := r.URL.Query().Get("limit")
+	if s == "" {
+		return def, nil
+	}
+
+	// compared before converting, a huge limit would wrap around in int32
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > int(max) {
+		return 0, errors.New("limit must be between 1 and " + strconv.Itoa(int(max)))
+	}
+
+	return int32(n), nil
+}
+
@@ -0,0 +1,35 @@
+package main
+
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+
+
+func TestParseLimit(t *testing.T) {
+
+	cases := []struct {
+		query string
+		want  int32
+		ok    bool
+	}{
+		{"", 20, true},
+		{"?limit=5", 5, true},
+		{"?limit=100", 100, true},
+		{"?limit=0", 0, false},
+		{"?limit=-3", 0, false},
+		{"?limit=101", 0, false},
+		{"?limit=abc", 0, false},
+		// wraps around to 1 in int32
+		{"?limit=4294967297", 0, false},
+	}
+
+	for _, c := range cases {
+		got, err := parseLimit(httptest.NewRequest("GET", "/api/chirps" + c.query, nil), 20, 100)
+		if (err == nil) != c.ok || got != c.want {
+			t.Errorf("%q: got %d, %v", c.query, got, err)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"time"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/DylanCoon99/bootdev-server/internal/links"
+	"github.com/google/uuid"
+)
+
+
+
+// Handlers never marshal database.User directly: it carries the password
+// hash and moderation state. Every user shaped response goes through one of
+// the types below instead, see responses_test.go.
+
+
+
+// userResponse is a user's own view of their account.
+type userResponse struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	Role        string    `json:"role"`
+	Handle      *string   `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+}
+
+
+func newUserResponse(user database.User) userResponse {
+	res := userResponse {
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: user.IsChirpyRed,
+		Role:        user.Role,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarUrl,
+	}
+	if user.Handle.Valid {
+		res.Handle = &user.Handle.String
+	}
+	return res
+}
+
+
+
+// loginResponse is the user plus the tokens they just got.
+type loginResponse struct {
+	userResponse
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+
+
+// adminUserResponse is the view of a user that admins get back, including
+// moderation state that regular users never see.
+type adminUserResponse struct {
+	userResponse
+	SuspendedUntil   *time.Time `json:"suspended_until"`
+	SuspensionReason string     `json:"suspension_reason"`
+	BannedAt         *time.Time `json:"banned_at"`
+	BanReason        string     `json:"ban_reason"`
+	ChirpsHidden     bool       `json:"chirps_hidden"`
+}
+
+
+func newAdminUserResponse(user database.User) adminUserResponse {
+	return adminUserResponse {
+		userResponse:     newUserResponse(user),
+		SuspendedUntil:   nullTimePtr(user.SuspendedUntil),
+		SuspensionReason: user.SuspensionReason,
+		BannedAt:         nullTimePtr(user.BannedAt),
+		BanReason:        user.BanReason,
+		ChirpsHidden:     user.ChirpsHidden,
+	}
+}
+
+
+
+// publicUserResponse is what anyone may see about a user. It must never grow
+// an email or anything else private.
+type publicUserResponse struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      *string   `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+
+func newPublicUserResponse(user database.User) publicUserResponse {
+	res := publicUserResponse {
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarUrl,
+		IsChirpyRed: user.IsChirpyRed,
+	}
+	if user.Handle.Valid {
+		res.Handle = &user.Handle.String
+	}
+	return res
+}
+
+
+
+// chirpAuthor is embedded in every chirp so clients can show a name instead
+// of a UUID.
+type chirpAuthor struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      *string   `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
+}
+
+
+func newChirpAuthor(user database.User) *chirpAuthor {
+	public := newPublicUserResponse(user)
+	return &chirpAuthor {
+		ID:          public.ID,
+		Handle:      public.Handle,
+		DisplayName: public.DisplayName,
+		AvatarURL:   public.AvatarURL,
+	}
+}
+
+
+type chirpResponse struct {
+	ChirpID   uuid.UUID       `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Body      string          `json:"body"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Author    *chirpAuthor    `json:"author"`
+	Media     []mediaResponse `json:"media"`
+	// filled in once the background fetch is done
+	LinkPreview *links.Preview `json:"link_preview"`
+	// only ever set on the author's own scheduled chirps
+	PublishAt *time.Time `json:"publish_at"`
+	// a rechirp has an empty body and carries the original here, with its
+	// own author
+	RechirpOf    *chirpResponse `json:"rechirp_of"`
+	QuoteOf      *chirpResponse `json:"quote_of"`
+	RechirpCount int64          `json:"rechirp_count"`
+	Poll         *pollResponse  `json:"poll"`
+	// only set when listing an author's chirps
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+
+
+// chirpResponses turns chirps into their JSON form, loading everything that
+// is shown alongside them in as few queries as possible.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, chirps []database.Chirp) ([]chirpResponse, error) {
+	return cfg.buildChirpResponses(ctx, chirps, true)
+}
+
+
+// buildChirpResponses does the work for chirpResponses. Rechirped and quoted
+// chirps are embedded one level deep: a quote of a quote shows the chirp it
+// quotes, but not what that one quotes in turn.
+func (cfg *apiConfig) buildChirpResponses(ctx context.Context, chirps []database.Chirp, embed bool) ([]chirpResponse, error) {
+
+	res := make([]chirpResponse, 0, len(chirps))
+	if len(chirps) == 0 {
+		return res, nil
+	}
+
+	userIDs := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, chirp := range chirps {
+		if !seen[chirp.UserID] {
+			seen[chirp.UserID] = true
+			userIDs = append(userIDs, chirp.UserID)
+		}
+	}
+
+	users, err := cfg.DBQueries.ListUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	authors := map[uuid.UUID]*chirpAuthor{}
+	for _, user := range users {
+		authors[user.ID] = newChirpAuthor(user)
+	}
+
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ChirpID)
+	}
+
+	attachments, err := cfg.DBQueries.ListMediaByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaByChirp := map[uuid.UUID][]mediaResponse{}
+	for _, m := range attachments {
+		mediaByChirp[m.ChirpID.UUID] = append(mediaByChirp[m.ChirpID.UUID], cfg.newMediaResponse(m))
+	}
+
+	urls := []string{}
+	for _, chirp := range chirps {
+		if u := firstURL(chirp.Body); u != "" {
+			urls = append(urls, u)
+		}
+	}
+
+	previews := map[string]*links.Preview{}
+	if len(urls) > 0 {
+		cached, err := cfg.DBQueries.ListLinkPreviewsByURLs(ctx, urls)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range cached {
+			previews[p.Url] = newLinkPreview(p)
+		}
+	}
+
+	shortLinks, err := cfg.DBQueries.ListShortLinksByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := map[uuid.UUID]map[string]string{}
+	for _, l := range shortLinks {
+		if codes[l.ChirpID] == nil {
+			codes[l.ChirpID] = map[string]string{}
+		}
+		codes[l.ChirpID][l.Url] = l.Code
+	}
+
+	counts, err := cfg.DBQueries.CountRechirps(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rechirpCounts := map[uuid.UUID]int64{}
+	for _, c := range counts {
+		rechirpCounts[c.RechirpOf.UUID] = c.Rechirps
+	}
+
+	polls, err := cfg.chirpPolls(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	embedded := map[uuid.UUID]*chirpResponse{}
+	if embed {
+		refIDs := []uuid.UUID{}
+		for _, chirp := range chirps {
+			if chirp.RechirpOf.Valid {
+				refIDs = append(refIDs, chirp.RechirpOf.UUID)
+			}
+			if chirp.QuoteOf.Valid {
+				refIDs = append(refIDs, chirp.QuoteOf.UUID)
+			}
+		}
+
+		if len(refIDs) > 0 {
+			viewer, _ := principalFromContext(ctx)
+			refs, err := cfg.DBQueries.ListEmbeddableChirps(ctx, database.ListEmbeddableChirpsParams {
+				ChirpIds: refIDs,
+				ViewerID: viewer.UserID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			refResponses, err := cfg.buildChirpResponses(ctx, refs, false)
+			if err != nil {
+				return nil, err
+			}
+			for i := range refResponses {
+				embedded[refResponses[i].ChirpID] = &refResponses[i]
+			}
+		}
+	}
+
+	for _, chirp := range chirps {
+		media := mediaByChirp[chirp.ChirpID]
+
+		// links are shown in their short form, chirps from before short
+		// links existed keep the original
+		body := links.Rewrite(chirp.Body, func(u string) string {
+			code, ok := codes[chirp.ChirpID][u]
+			if !ok {
+				return u
+			}
+			return cfg.shortLinkURL(code)
+		})
+
+		if media == nil {
+			media = []mediaResponse{}
+		}
+		res = append(res, chirpResponse {
+			ChirpID:      chirp.ChirpID,
+			CreatedAt:    chirp.CreatedAt,
+			UpdatedAt:    chirp.UpdatedAt,
+			Body:         body,
+			UserID:       chirp.UserID,
+			Author:       authors[chirp.UserID],
+			Media:        media,
+			LinkPreview:  previews[firstURL(chirp.Body)],
+			PublishAt:    nullTimePtr(chirp.PublishAt),
+			// missing references, such as a quoted chirp that has since
+			// been deleted, come out as null
+			RechirpOf:    embedded[chirp.RechirpOf.UUID],
+			QuoteOf:      embedded[chirp.QuoteOf.UUID],
+			RechirpCount: rechirpCounts[chirp.ChirpID],
+			Poll:         polls[chirp.ChirpID],
+		})
+	}
+
+	return res, nil
+}
+
+
+// chirpResponseFor is chirpResponses for a single chirp.
+func (cfg *apiConfig) chirpResponseFor(ctx context.Context, chirp database.Chirp) (chirpResponse, error) {
+	res, err := cfg.chirpResponses(ctx, []database.Chirp{chirp})
+	if err != nil {
+		return chirpResponse{}, err
+	}
+	return res[0], nil
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"github.com/DylanCoon99/bootdev-server/internal/auth"
+	"github.com/DylanCoon99/bootdev-server/internal/database"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// These tests make sure no response body ever carries a password hash. The
+// handlers run against a fake database/sql driver that answers every sqlc
+// query with a single row built from the model the query returns, so the
+// user rows they see carry a real bcrypt hash.
+
+const testPassword = "04234"
+
+var fakeUserID = uuid.MustParse("0b2c3b2e-4a1f-4f5e-9f57-0a2c1c8e6b11")
+
+// fakeRows maps the sqlc query name to the model it returns. These are
+// answered by every fake database, tests add their own through newFakeConfig.
+// Fields set on a model are returned as they are, the rest are made up by
+// fakeValue, and a slice of models returns one row each.
+var fakeRows = map[string]interface{}{
+	"CreateUser":         database.User{},
+	"GetUser":            database.User{},
+	"GetUserByID":        database.User{},
+	"GetUserByHandle":    database.User{},
+	"UpdateUser":         database.User{},
+	"UpdateUserProfile":  database.User{},
+	"SetUserRole":        database.User{},
+	"ListUsersByIDs":     database.User{},
+	"CreateRefreshToken": database.RefreshToken{},
+}
+
+var queryNamePattern = regexp.MustCompile(`-- name: (\w+)`)
+
+// fakeCall is a query the fake database ran, or BEGIN, COMMIT or ROLLBACK
+type fakeCall struct {
+	name string
+	args []driver.Value
+}
+
+var (
+	fakeCallsMu sync.Mutex
+	fakeCalls   []fakeCall
+)
+
+func recordFakeCall(name string, args []driver.Value) {
+	fakeCallsMu.Lock()
+	defer fakeCallsMu.Unlock()
+	fakeCalls = append(fakeCalls, fakeCall{name: name, args: args})
+}
+
+// takeFakeCalls returns what the fake database ran since the last call.
+func takeFakeCalls() []fakeCall {
+	fakeCallsMu.Lock()
+	defer fakeCallsMu.Unlock()
+	calls := fakeCalls
+	fakeCalls = nil
+	return calls
+}
+
+type fakeDriver struct {
+	hash string
+	rows map[string]interface{}
+}
+
+type fakeConn struct {
+	hash string
+	rows map[string]interface{}
+}
+
+type fakeStmt struct {
+	hash  string
+	rows  map[string]interface{}
+	query string
+}
+
+type fakeResultRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (d fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{hash: d.hash, rows: d.rows}, nil
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{hash: c.hash, rows: c.rows, query: query}, nil
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	recordFakeCall("BEGIN", nil)
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	recordFakeCall("COMMIT", nil)
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	recordFakeCall("ROLLBACK", nil)
+	return nil
+}
+
+func (s fakeStmt) Close() error {
+	return nil
+}
+
+func (s fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if m := queryNamePattern.FindStringSubmatch(s.query); m != nil {
+		recordFakeCall(m[1], args)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+
+	rows := &fakeResultRows{}
+
+	m := queryNamePattern.FindStringSubmatch(s.query)
+	if m == nil {
+		return rows, nil
+	}
+	recordFakeCall(m[1], args)
+
+	model, ok := s.rows[m[1]]
+	if !ok {
+		// unknown queries return nothing, i.e. sql.ErrNoRows
+		return rows, nil
+	}
+
+	models := reflect.ValueOf(model)
+	if models.Kind() != reflect.Slice {
+		models = reflect.ValueOf([]interface{}{model})
+	}
+
+	for i := 0; i < models.Len(); i++ {
+		v := reflect.ValueOf(models.Index(i).Interface())
+		rows.columns = nil
+		values := []driver.Value{}
+		for j := 0; j < v.NumField(); j++ {
+			rows.columns = append(rows.columns, v.Type().Field(j).Name)
+			values = append(values, fieldValue(v.Type().Field(j), v.Field(j), s.hash))
+		}
+		rows.values = append(rows.values, values)
+	}
+
+	return rows, nil
+}
+
+func (r *fakeResultRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeResultRows) Close() error {
+	return nil
+}
+
+func (r *fakeResultRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// fieldValue returns a field the test set as it is, or a made up value
+func fieldValue(field reflect.StructField, v reflect.Value, hash string) driver.Value {
+
+	if v.IsZero() {
+		return fakeValue(field, hash)
+	}
+
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, _ := valuer.Value()
+		return value
+	}
+
+	switch v.Kind() {
+	case reflect.Int32, reflect.Int64:
+		return v.Int()
+	}
+	return v.Interface()
+}
+
+// fakeValue fills a model field with something that scans into it
+func fakeValue(field reflect.StructField, hash string) driver.Value {
+
+	switch field.Type {
+	case reflect.TypeOf(uuid.UUID{}):
+		return fakeUserID.String()
+	case reflect.TypeOf(time.Time{}):
+		return time.Now()
+	case reflect.TypeOf(""):
+		switch field.Name {
+		case "HashedPassword":
+			return hash
+		case "Email":
+			return "walt@breakingbad.com"
+		case "Role":
+			return auth.RoleUser
+		}
+		return "x"
+	case reflect.TypeOf(false):
+		return false
+	case reflect.TypeOf(int32(0)), reflect.TypeOf(int64(0)):
+		return int64(1)
+	}
+
+	// sql.Null* types
+	return nil
+}
+
+var fakeDriverCount = 0
+
+// newFakeConfig returns a config backed by a fake database that answers the
+// queries in fakeRows plus the given rows, which win over fakeRows.
+func newFakeConfig(t *testing.T, rows map[string]interface{}) (*apiConfig, string) {
+
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("couldn't hash password: %v", err)
+	}
+
+	all := map[string]interface{}{}
+	for name, model := range fakeRows {
+		all[name] = model
+	}
+	for name, model := range rows {
+		all[name] = model
+	}
+
+	fakeDriverCount++
+	name := "fake" + string(rune('a'+fakeDriverCount))
+	sql.Register(name, fakeDriver{hash: hash, rows: all})
+
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("couldn't open fake db: %v", err)
+	}
+
+	cfg := &apiConfig{
+		DB:        db,
+		DBQueries: database.New(db),
+		jwtSecret: "secret",
+		Platform:  "dev",
+	}
+	return cfg, hash
+}
+
+// asCaller returns r as sent by an authenticated user with the given role.
+func asCaller(r *http.Request, userID uuid.UUID, role string) *http.Request {
+	p := principal{UserID: userID, Claims: auth.Claims{UserID: userID, Role: role}}
+	return r.WithContext(context.WithValue(r.Context(), principalKey, p))
+}
+
+func assertNoPasswordHash(t *testing.T, name string, body []byte, hash string) {
+
+	if len(body) == 0 {
+		t.Fatalf("%s: empty response body", name)
+	}
+
+	s := string(body)
+	if strings.Contains(s, "hashed_password") || strings.Contains(s, hash) || strings.Contains(s, "$2a$") {
+		t.Fatalf("%s: response body leaks the password hash: %s", name, s)
+	}
+}
+
+func TestResponsesNeverContainPasswordHash(t *testing.T) {
+
+	cfg, hash := newFakeConfig(t, nil)
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		request *http.Request
+		public  bool
+	}{
+		{
+			name:    "createUserHandler",
+			handler: cfg.createUserHandler,
+			request: httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"email":"walt@breakingbad.com","password":"04234"}`)),
+		},
+		{
+			name:    "loginHandler",
+			handler: cfg.loginHandler,
+			request: httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"email":"walt@breakingbad.com","password":"04234"}`)),
+		},
+		{
+			name:    "handlerUpdateUser",
+			handler: cfg.handlerUpdateUser,
+			request: asCaller(httptest.NewRequest("PUT", "/api/users", strings.NewReader(`{"email":"walt@breakingbad.com","password":"04234"}`)), fakeUserID, auth.RoleUser),
+		},
+		{
+			name:    "handlerUpdateProfile",
+			handler: cfg.handlerUpdateProfile,
+			request: asCaller(httptest.NewRequest("PUT", "/api/users/me/profile", strings.NewReader(`{"handle":"heisenberg"}`)), fakeUserID, auth.RoleUser),
+			public:  true,
+		},
+		{
+			name:    "handlerGetProfile",
+			handler: cfg.handlerGetProfile,
+			request: httptest.NewRequest("GET", "/api/users/heisenberg", nil),
+			public:  true,
+		},
+		{
+			name:    "handlerSetUserRole",
+			handler: cfg.handlerSetUserRole,
+			request: asCaller(httptest.NewRequest("PUT", "/admin/users/x/role", strings.NewReader(`{"role":"moderator"}`)), fakeUserID, auth.RoleAdmin),
+		},
+	}
+
+	for _, c := range cases {
+		c.request.SetPathValue("userID", uuid.NewString())
+		c.request.SetPathValue("handle", "heisenberg")
+
+		w := httptest.NewRecorder()
+		c.handler(w, c.request)
+
+		if w.Code >= 300 {
+			t.Fatalf("%s: unexpected status %d: %s", c.name, w.Code, w.Body.String())
+		}
+
+		assertNoPasswordHash(t, c.name, w.Body.Bytes(), hash)
+
+		if c.public && bytes.Contains(w.Body.Bytes(), []byte("walt@breakingbad.com")) {
+			t.Fatalf("%s: public response leaks the email: %s", c.name, w.Body.String())
+		}
+	}
+}
+
+func TestUserSerializersNeverContainPasswordHash(t *testing.T) {
+
+	hash, _ := auth.HashPassword(testPassword)
+
+	user := database.User{
+		ID:             uuid.New(),
+		Email:          "walt@breakingbad.com",
+		HashedPassword: hash,
+	}
+
+	values := map[string]interface{}{
+		"database.User":      user,
+		"userResponse":       newUserResponse(user),
+		"loginResponse":      loginResponse{userResponse: newUserResponse(user)},
+		"adminUserResponse":  newAdminUserResponse(user),
+		"publicUserResponse": newPublicUserResponse(user),
+	}
+
+	for name, v := range values {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		assertNoPasswordHash(t, name, b, hash)
+	}
+}
@@ -0,0 +1,107 @@
+-- name: ScheduleUserDeletion :one
+UPDATE users SET deletion_requested_at = NOW(),
+delete_after = $2,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: CancelUserDeletion :one
+UPDATE users SET deletion_requested_at = NULL,
+delete_after = NULL,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: PurgeDeletedUsers :execrows
+DELETE FROM users
+WHERE delete_after < NOW();
+
+
+-- name: ListExportFilesOfUsersDueForPurge :many
+SELECT export_jobs.file_path FROM export_jobs
+JOIN users ON users.id = export_jobs.user_id
+WHERE users.delete_after < NOW()
+AND export_jobs.file_path <> '';
+
+
+-- name: ListMediaOfUsersDueForPurge :many
+SELECT media.storage_key FROM media
+JOIN users ON users.id = media.user_id
+WHERE users.delete_after < NOW();
+
+
+-- name: CountChirpsByUser :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1;
+
+
+-- name: ListRefreshTokensByUser :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+
+-- name: CreateExportJob :one
+INSERT INTO export_jobs (id, created_at, updated_at, user_id)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1
+)
+RETURNING *;
+
+
+-- name: GetExportJob :one
+SELECT * FROM export_jobs
+WHERE id = $1
+AND user_id = $2;
+
+
+-- name: GetActiveExportJob :one
+SELECT * FROM export_jobs
+WHERE user_id = $1
+AND status IN ('pending', 'running')
+ORDER BY created_at DESC
+LIMIT 1;
+
+
+-- name: ClaimExportJob :one
+-- running jobs that haven't finished by stale_before were left behind by a
+-- crashed worker and are picked up again
+UPDATE export_jobs SET status = 'running',
+updated_at = NOW()
+WHERE id = (
+    SELECT id FROM export_jobs
+    WHERE status = 'pending'
+    OR (status = 'running' AND updated_at < sqlc.arg(stale_before)::timestamp)
+    ORDER BY created_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+
+-- name: CompleteExportJob :exec
+UPDATE export_jobs SET status = 'done',
+file_path = $2,
+completed_at = NOW(),
+updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: FailExportJob :exec
+UPDATE export_jobs SET status = 'failed',
+error = $2,
+completed_at = NOW(),
+updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: ListExpiredExportJobs :many
+SELECT * FROM export_jobs
+WHERE completed_at < sqlc.arg(completed_before)::timestamp;
+
+
+-- name: DeleteExportJob :exec
+DELETE FROM export_jobs
+WHERE id = $1;
@@ -0,0 +1,49 @@
+-- name: BlockUser :exec
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+
+-- name: UnblockUser :exec
+DELETE FROM user_blocks
+WHERE blocker_id = $1
+AND blocked_id = $2;
+
+
+-- name: MuteUser :exec
+INSERT INTO user_mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+
+-- name: UnmuteUser :exec
+DELETE FROM user_mutes
+WHERE muter_id = $1
+AND muted_id = $2;
+
+
+-- name: IsBlocked :one
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE blocker_id = $1
+    AND blocked_id = $2
+);
+
+
+-- name: IsBlockedByAnyHandle :one
+-- handles are lowercase, as findMentions returns them
+SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    JOIN users ON users.id = user_blocks.blocker_id
+    WHERE user_blocks.blocked_id = sqlc.arg(blocked_id)
+    AND LOWER(users.handle) = ANY(sqlc.arg(handles)::text[])
+);
+
+
+-- name: ListHiddenAuthors :many
+-- everyone whose chirps the user doesn't want to see
+SELECT blocked_id AS user_id FROM user_blocks
+WHERE blocker_id = $1
+UNION
+SELECT muted_id AS user_id FROM user_mutes
+WHERE muter_id = $1;
@@ -0,0 +1,88 @@
+-- name: CreateBookmark :one
+-- returns no rows if the collection isn't the user's
+INSERT INTO bookmarks (user_id, chirp_id, collection_id, created_at)
+SELECT sqlc.arg(user_id)::uuid, sqlc.arg(chirp_id)::uuid, sqlc.narg(collection_id)::uuid, NOW()
+WHERE sqlc.narg(collection_id)::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM bookmark_collections
+    WHERE bookmark_collections.id = sqlc.narg(collection_id)::uuid
+    AND bookmark_collections.user_id = sqlc.arg(user_id)::uuid
+)
+RETURNING *;
+
+
+-- name: MoveBookmark :one
+-- a NULL collection_id takes the bookmark out of its collection
+UPDATE bookmarks SET collection_id = sqlc.narg(collection_id)::uuid
+WHERE bookmarks.user_id = sqlc.arg(user_id)
+AND bookmarks.chirp_id = sqlc.arg(chirp_id)
+AND (sqlc.narg(collection_id)::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM bookmark_collections
+    WHERE bookmark_collections.id = sqlc.narg(collection_id)::uuid
+    AND bookmark_collections.user_id = sqlc.arg(user_id)
+))
+RETURNING *;
+
+
+-- name: DeleteBookmark :one
+DELETE FROM bookmarks
+WHERE user_id = $1
+AND chirp_id = $2
+RETURNING *;
+
+
+-- name: ListBookmarks :many
+-- bookmarks of deleted or hidden chirps drop out here, and go for good when
+-- the chirp is purged
+SELECT sqlc.embed(chirps), bookmarks.created_at AS bookmarked_at, bookmarks.collection_id
+FROM bookmarks
+JOIN chirps ON chirps.chirp_id = bookmarks.chirp_id
+JOIN users ON users.id = chirps.user_id
+WHERE bookmarks.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(collection_id)::uuid IS NULL OR bookmarks.collection_id = sqlc.narg(collection_id)::uuid)
+AND (sqlc.narg(before)::timestamp IS NULL OR bookmarks.created_at < sqlc.narg(before)::timestamp)
+AND chirps.deleted_at IS NULL
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg(user_id))
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+ORDER BY bookmarks.created_at DESC
+LIMIT sqlc.arg(max_results);
+
+
+-- name: CreateBookmarkCollection :one
+INSERT INTO bookmark_collections (id, created_at, updated_at, user_id, name)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2
+)
+RETURNING *;
+
+
+-- name: ListBookmarkCollections :many
+-- counts what ListBookmarks would list for the collection
+SELECT bookmark_collections.*, (
+    SELECT COUNT(*) FROM bookmarks
+    JOIN chirps ON chirps.chirp_id = bookmarks.chirp_id
+    JOIN users ON users.id = chirps.user_id
+    WHERE bookmarks.collection_id = bookmark_collections.id
+    AND chirps.deleted_at IS NULL
+    AND (chirps.hidden_at IS NULL OR chirps.user_id = bookmark_collections.user_id)
+    AND users.chirps_hidden = FALSE
+    AND users.deletion_requested_at IS NULL
+) AS bookmarks
+FROM bookmark_collections
+WHERE bookmark_collections.user_id = $1
+ORDER BY bookmark_collections.name ASC;
+
+
+-- name: RenameBookmarkCollection :one
+UPDATE bookmark_collections SET name = $3,
+updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+RETURNING *;
+
+
+-- name: DeleteBookmarkCollection :one
+DELETE FROM bookmark_collections
+WHERE id = $1
+AND user_id = $2
+RETURNING *;
@@ -0,0 +1,35 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, user_id, body)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2
+)
+RETURNING *;
+
+
+-- name: ListDrafts :many
+SELECT * FROM drafts
+WHERE user_id = $1
+ORDER BY updated_at DESC
+LIMIT $2;
+
+
+-- name: GetDraft :one
+SELECT * FROM drafts
+WHERE id = $1
+AND user_id = $2;
+
+
+-- name: UpdateDraft :one
+UPDATE drafts SET body = $3,
+updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+RETURNING *;
+
+
+-- name: DeleteDraft :one
+-- also used to take a draft when publishing it
+DELETE FROM drafts
+WHERE id = $1
+AND user_id = $2
+RETURNING *;
@@ -0,0 +1,82 @@
+-- Everything below feeds buildExportArchive, each query returns all of a
+-- user's rows of one kind.
+
+
+-- name: ListDraftsForExport :many
+SELECT * FROM drafts
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+
+-- name: ListConversationsForExport :many
+SELECT conversations.* FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversation_participants.user_id = $1
+ORDER BY conversations.created_at ASC;
+
+
+-- name: ListMessagesForExport :many
+-- the whole of every conversation the user is in, not just what they sent
+SELECT messages.* FROM messages
+JOIN conversation_participants ON conversation_participants.conversation_id = messages.conversation_id
+WHERE conversation_participants.user_id = $1
+ORDER BY messages.created_at ASC;
+
+
+-- name: ListBookmarksForExport :many
+SELECT bookmarks.chirp_id, bookmarks.created_at, bookmark_collections.name AS collection
+FROM bookmarks
+LEFT JOIN bookmark_collections ON bookmark_collections.id = bookmarks.collection_id
+WHERE bookmarks.user_id = $1
+ORDER BY bookmarks.created_at ASC;
+
+
+-- name: ListBookmarkCollectionsForExport :many
+SELECT * FROM bookmark_collections
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+
+-- name: ListPollVotesForExport :many
+SELECT poll_votes.chirp_id, poll_votes.created_at, poll_votes.updated_at, poll_options.text AS option
+FROM poll_votes
+JOIN poll_options ON poll_options.id = poll_votes.option_id
+WHERE poll_votes.user_id = $1
+ORDER BY poll_votes.created_at ASC;
+
+
+-- name: ListListsForExport :many
+SELECT * FROM lists
+WHERE owner_id = $1
+ORDER BY created_at ASC;
+
+
+-- name: ListListMembersForExport :many
+SELECT list_members.* FROM list_members
+JOIN lists ON lists.id = list_members.list_id
+WHERE lists.owner_id = $1
+ORDER BY list_members.added_at ASC;
+
+
+-- name: ListListSubscriptionsForExport :many
+SELECT * FROM list_subscriptions
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+
+-- name: ListBlocksForExport :many
+SELECT blocked_id AS user_id, created_at FROM user_blocks
+WHERE blocker_id = $1
+ORDER BY created_at ASC;
+
+
+-- name: ListMutesForExport :many
+SELECT muted_id AS user_id, created_at FROM user_mutes
+WHERE muter_id = $1
+ORDER BY created_at ASC;
+
+
+-- name: ListMessageBlocksForExport :many
+SELECT blocked_id AS user_id, created_at FROM message_blocks
+WHERE blocker_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,156 @@
+-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, owner_id, name, description, is_private)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+RETURNING *;
+
+
+-- name: UpdateList :one
+UPDATE lists SET name = $3,
+description = $4,
+is_private = $5,
+updated_at = NOW()
+WHERE id = $1
+AND owner_id = $2
+RETURNING *;
+
+
+-- name: DeleteList :one
+DELETE FROM lists
+WHERE id = $1
+AND owner_id = $2
+RETURNING *;
+
+
+-- name: GetListForViewer :one
+-- private lists only exist for their owner
+SELECT sqlc.embed(lists),
+(SELECT COUNT(*) FROM list_members WHERE list_members.list_id = lists.id) AS member_count,
+(SELECT COUNT(*) FROM list_subscriptions WHERE list_subscriptions.list_id = lists.id) AS subscriber_count,
+EXISTS (
+    SELECT 1 FROM list_subscriptions
+    WHERE list_subscriptions.list_id = lists.id
+    AND list_subscriptions.user_id = sqlc.arg(viewer_id)
+) AS subscribed
+FROM lists
+WHERE lists.id = sqlc.arg(list_id)
+AND (lists.is_private = FALSE OR lists.owner_id = sqlc.arg(viewer_id));
+
+
+-- name: ListListsForUser :many
+-- the user's own lists and the public lists they subscribe to
+SELECT sqlc.embed(lists),
+(SELECT COUNT(*) FROM list_members WHERE list_members.list_id = lists.id) AS member_count,
+(SELECT COUNT(*) FROM list_subscriptions WHERE list_subscriptions.list_id = lists.id) AS subscriber_count,
+EXISTS (
+    SELECT 1 FROM list_subscriptions
+    WHERE list_subscriptions.list_id = lists.id
+    AND list_subscriptions.user_id = sqlc.arg(user_id)
+) AS subscribed
+FROM lists
+WHERE lists.owner_id = sqlc.arg(user_id)
+OR (lists.is_private = FALSE AND EXISTS (
+    SELECT 1 FROM list_subscriptions
+    WHERE list_subscriptions.list_id = lists.id
+    AND list_subscriptions.user_id = sqlc.arg(user_id)
+))
+ORDER BY lists.name ASC;
+
+
+-- name: AddListMember :one
+-- only the owner can add members, returns no rows otherwise
+INSERT INTO list_members (list_id, user_id, added_at)
+SELECT lists.id, sqlc.arg(user_id)::uuid, NOW()
+FROM lists
+WHERE lists.id = sqlc.arg(list_id)::uuid
+AND lists.owner_id = sqlc.arg(owner_id)::uuid
+RETURNING *;
+
+
+-- name: RemoveListMember :one
+DELETE FROM list_members
+USING lists
+WHERE list_members.list_id = lists.id
+AND lists.id = sqlc.arg(list_id)
+AND lists.owner_id = sqlc.arg(owner_id)
+AND list_members.user_id = sqlc.arg(user_id)
+RETURNING list_members.*;
+
+
+-- name: LockListForMembers :one
+-- serializes adding members per list so two requests can't both squeeze
+-- under the limit, returns no rows unless owner_id owns the list
+SELECT id FROM lists
+WHERE id = sqlc.arg(list_id)
+AND owner_id = sqlc.arg(owner_id)
+FOR UPDATE;
+
+
+-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1;
+
+
+-- name: ListListMembers :many
+SELECT users.* FROM list_members
+JOIN users ON users.id = list_members.user_id
+WHERE list_members.list_id = $1
+ORDER BY list_members.added_at ASC;
+
+
+-- name: SubscribeToList :one
+-- public lists of other users only
+INSERT INTO list_subscriptions (list_id, user_id, created_at)
+SELECT lists.id, sqlc.arg(user_id)::uuid, NOW()
+FROM lists
+WHERE lists.id = sqlc.arg(list_id)::uuid
+AND lists.is_private = FALSE
+AND lists.owner_id <> sqlc.arg(user_id)::uuid
+RETURNING *;
+
+
+-- name: UnsubscribeFromList :one
+DELETE FROM list_subscriptions
+WHERE list_id = $1
+AND user_id = $2
+RETURNING *;
+
+
+-- name: ListListChirps :many
+-- the list's members' chirps, newest first, with the same visibility rules
+-- as ListChirps
+SELECT chirps.* FROM chirps
+JOIN list_members ON list_members.user_id = chirps.user_id
+JOIN users ON users.id = chirps.user_id
+WHERE list_members.list_id = sqlc.arg(list_id)
+AND (sqlc.narg(before)::timestamp IS NULL OR chirps.created_at < sqlc.narg(before)::timestamp)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg(viewer_id))
+AND (chirps.publish_at IS NULL OR chirps.user_id = sqlc.arg(viewer_id))
+AND chirps.deleted_at IS NULL
+-- rechirps go away with the chirp they share, by the same rules as
+-- ListEmbeddableChirps
+AND (chirps.rechirp_of IS NULL OR EXISTS (
+    SELECT 1 FROM chirps original
+    JOIN users original_author ON original_author.id = original.user_id
+    WHERE original.chirp_id = chirps.rechirp_of
+    AND original.deleted_at IS NULL
+    AND original.hidden_at IS NULL
+    AND original.publish_at IS NULL
+    AND original_author.chirps_hidden = FALSE
+    AND original_author.deletion_requested_at IS NULL
+))
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = sqlc.arg(viewer_id)
+    AND user_blocks.blocked_id IN (chirps.user_id, (SELECT original.user_id FROM chirps original WHERE original.chirp_id = chirps.rechirp_of))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE user_mutes.muter_id = sqlc.arg(viewer_id)
+    AND user_mutes.muted_id IN (chirps.user_id, (SELECT original.user_id FROM chirps original WHERE original.chirp_id = chirps.rechirp_of))
+)
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(max_results);
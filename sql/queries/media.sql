@@ -0,0 +1,33 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, created_at, user_id, storage_key, content_type, size_bytes, width, height)
+VALUES (
+    $1, NOW(), $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+
+-- name: AttachMedia :one
+-- only the uploader can attach a file, and only to one chirp
+UPDATE media SET chirp_id = sqlc.arg(chirp_id), position = sqlc.arg(position)
+WHERE id = sqlc.arg(id)
+AND user_id = sqlc.arg(user_id)
+AND chirp_id IS NULL
+RETURNING *;
+
+
+-- name: GetMediaByStorageKey :one
+SELECT * FROM media
+WHERE storage_key = $1;
+
+
+-- name: ListMediaByChirpIDs :many
+SELECT * FROM media
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position;
+
+
+-- name: PurgeUnattachedMedia :many
+DELETE FROM media
+WHERE chirp_id IS NULL
+AND created_at < sqlc.arg(created_before)::timestamp
+RETURNING storage_key;
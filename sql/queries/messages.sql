@@ -0,0 +1,146 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, is_group)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2
+)
+RETURNING *;
+
+
+-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW());
+
+
+-- name: FindDirectConversation :one
+SELECT conversations.* FROM conversations
+JOIN conversation_participants a ON a.conversation_id = conversations.id AND a.user_id = sqlc.arg(user_a)
+JOIN conversation_participants b ON b.conversation_id = conversations.id AND b.user_id = sqlc.arg(user_b)
+WHERE conversations.is_group = FALSE
+LIMIT 1;
+
+
+-- name: LockDirectConversation :exec
+-- serializes creating the direct conversation of two users until the end of
+-- the transaction, whichever of them starts it
+SELECT pg_advisory_xact_lock(hashtextextended(
+    LEAST(sqlc.arg(user_a)::uuid, sqlc.arg(user_b)::uuid)::text || GREATEST(sqlc.arg(user_a)::uuid, sqlc.arg(user_b)::uuid)::text,
+    0
+));
+
+
+-- name: GetConversationForUser :one
+-- every conversation query goes through the participants table, so nobody
+-- outside a conversation can see it
+SELECT conversations.* FROM conversations
+JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+WHERE conversations.id = sqlc.arg(conversation_id)
+AND conversation_participants.user_id = sqlc.arg(user_id);
+
+
+-- name: ListConversationsForUser :many
+SELECT conversations.*,
+(
+    SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = conversations.id
+    AND messages.sender_id <> sqlc.arg(user_id)
+    AND (me.last_read_at IS NULL OR messages.created_at > me.last_read_at)
+) AS unread_count
+FROM conversations
+JOIN conversation_participants me ON me.conversation_id = conversations.id AND me.user_id = sqlc.arg(user_id)
+WHERE (sqlc.narg(before)::timestamp IS NULL OR conversations.updated_at < sqlc.narg(before)::timestamp)
+ORDER BY conversations.updated_at DESC
+LIMIT sqlc.arg(max_results);
+
+
+-- name: CountUnreadMessages :one
+-- the same count as unread_count in ListConversationsForUser
+SELECT COUNT(*) FROM messages
+JOIN conversation_participants me ON me.conversation_id = messages.conversation_id AND me.user_id = sqlc.arg(user_id)
+WHERE messages.conversation_id = sqlc.arg(conversation_id)
+AND messages.sender_id <> sqlc.arg(user_id)
+AND (me.last_read_at IS NULL OR messages.created_at > me.last_read_at);
+
+
+-- name: ListLastMessages :many
+SELECT DISTINCT ON (conversation_id) * FROM messages
+WHERE conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+ORDER BY conversation_id, created_at DESC;
+
+
+-- name: ListConversationParticipants :many
+SELECT * FROM conversation_participants
+WHERE conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+ORDER BY joined_at ASC;
+
+
+-- name: CreateMessage :one
+-- only participants can post
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+SELECT gen_random_uuid(), NOW(), conversation_participants.conversation_id, conversation_participants.user_id, sqlc.arg(body)::text
+FROM conversation_participants
+WHERE conversation_participants.conversation_id = sqlc.arg(conversation_id)
+AND conversation_participants.user_id = sqlc.arg(sender_id)
+RETURNING *;
+
+
+-- name: TouchConversation :exec
+UPDATE conversations SET updated_at = NOW()
+WHERE id = $1;
+
+
+-- name: ListMessages :many
+SELECT messages.* FROM messages
+JOIN conversation_participants ON conversation_participants.conversation_id = messages.conversation_id
+WHERE messages.conversation_id = sqlc.arg(conversation_id)
+AND conversation_participants.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(before)::timestamp IS NULL OR messages.created_at < sqlc.narg(before)::timestamp)
+ORDER BY messages.created_at DESC
+LIMIT sqlc.arg(max_results);
+
+
+-- name: MarkConversationRead :one
+UPDATE conversation_participants SET last_read_at = NOW()
+WHERE conversation_id = $1
+AND user_id = $2
+RETURNING *;
+
+
+-- name: IsBlockedFromConversation :one
+-- whether anyone in the conversation has blocked the sender, a full block
+-- covers messages too
+SELECT (EXISTS (
+    SELECT 1 FROM message_blocks
+    JOIN conversation_participants ON conversation_participants.user_id = message_blocks.blocker_id
+    WHERE conversation_participants.conversation_id = sqlc.arg(conversation_id)
+    AND message_blocks.blocked_id = sqlc.arg(sender_id)
+) OR EXISTS (
+    SELECT 1 FROM user_blocks
+    JOIN conversation_participants ON conversation_participants.user_id = user_blocks.blocker_id
+    WHERE conversation_participants.conversation_id = sqlc.arg(conversation_id)
+    AND user_blocks.blocked_id = sqlc.arg(sender_id)
+))::boolean;
+
+
+-- name: IsBlockedByAny :one
+-- whether any of the users has blocked the sender
+SELECT (EXISTS (
+    SELECT 1 FROM message_blocks
+    WHERE message_blocks.blocker_id = ANY(sqlc.arg(user_ids)::uuid[])
+    AND message_blocks.blocked_id = sqlc.arg(sender_id)
+) OR EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = ANY(sqlc.arg(user_ids)::uuid[])
+    AND user_blocks.blocked_id = sqlc.arg(sender_id)
+))::boolean;
+
+
+-- name: BlockMessagesFrom :exec
+INSERT INTO message_blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+
+-- name: UnblockMessagesFrom :exec
+DELETE FROM message_blocks
+WHERE blocker_id = $1
+AND blocked_id = $2;
@@ -0,0 +1,78 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, chirp_id, reporter_id, reason, details)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+ON CONFLICT (chirp_id, reporter_id) DO NOTHING
+RETURNING *;
+
+
+-- name: CountOpenReports :one
+SELECT COUNT(*) FROM reports
+WHERE chirp_id = $1
+AND status = 'open';
+
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+
+-- name: ListReports :many
+SELECT reports.*, chirps.body AS chirp_body, chirps.user_id AS author_id, chirps.hidden_at AS chirp_hidden_at
+FROM reports
+JOIN chirps ON chirps.chirp_id = reports.chirp_id
+WHERE reports.status = $1
+ORDER BY reports.created_at ASC
+LIMIT $2;
+
+
+-- name: ResolveReport :one
+UPDATE reports SET status = $2,
+resolved_by = $3,
+resolved_at = NOW(),
+updated_at = NOW()
+WHERE id = $1
+AND status = 'open'
+RETURNING *;
+
+
+-- name: ResolveReportsForChirp :exec
+UPDATE reports SET status = $2,
+resolved_by = $3,
+resolved_at = NOW(),
+updated_at = NOW()
+WHERE chirp_id = $1
+AND status = 'open';
+
+
+-- name: HideChirp :one
+UPDATE chirps SET hidden_at = NOW(),
+updated_at = NOW()
+WHERE chirp_id = $1
+AND hidden_at IS NULL
+RETURNING *;
+
+
+-- name: UnhideChirp :one
+-- deleted chirps stay deleted, their authors took them down
+UPDATE chirps SET hidden_at = NULL,
+updated_at = NOW()
+WHERE chirp_id = $1
+AND hidden_at IS NOT NULL
+AND deleted_at IS NULL
+RETURNING *;
+
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, created_at, moderator_id, action, chirp_id, report_id, target_user_id, reason)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+
+-- name: ListModerationActions :many
+SELECT * FROM moderation_actions
+ORDER BY created_at DESC
+LIMIT $1;
@@ -0,0 +1,66 @@
+-- name: CreateNotification :one
+-- returns no rows if the user has muted this type, or blocked or muted the
+-- actor
+INSERT INTO notifications (id, created_at, user_id, type, actor_id, chirp_id, detail)
+SELECT gen_random_uuid(), NOW(), sqlc.arg(user_id)::uuid, sqlc.arg(type)::text, sqlc.narg(actor_id)::uuid, sqlc.narg(chirp_id)::uuid, sqlc.arg(detail)::text
+WHERE NOT EXISTS (
+    SELECT 1 FROM notification_mutes
+    WHERE notification_mutes.user_id = sqlc.arg(user_id)::uuid
+    AND notification_mutes.type = sqlc.arg(type)::text
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = sqlc.arg(user_id)::uuid
+    AND user_blocks.blocked_id = sqlc.narg(actor_id)::uuid
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE user_mutes.muter_id = sqlc.arg(user_id)::uuid
+    AND user_mutes.muted_id = sqlc.narg(actor_id)::uuid
+)
+RETURNING *;
+
+
+-- name: ListNotifications :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg(user_id)
+AND (sqlc.arg(unread_only)::boolean = FALSE OR read_at IS NULL)
+AND (sqlc.narg(before)::timestamp IS NULL OR created_at < sqlc.narg(before)::timestamp)
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_results);
+
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1
+AND read_at IS NULL;
+
+
+-- name: MarkNotificationRead :one
+UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1
+AND user_id = $2
+RETURNING *;
+
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = $1
+AND read_at IS NULL;
+
+
+-- name: ListNotificationMutes :many
+SELECT type FROM notification_mutes
+WHERE user_id = $1;
+
+
+-- name: MuteNotificationType :exec
+INSERT INTO notification_mutes (user_id, type)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+
+-- name: UnmuteNotificationType :exec
+DELETE FROM notification_mutes
+WHERE user_id = $1
+AND type = $2;
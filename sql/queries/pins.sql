@@ -0,0 +1,47 @@
+-- name: LockUserForPinning :exec
+-- serializes pinning per user so two requests can't both squeeze under
+-- the limit
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE;
+
+
+-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM pinned_chirps
+WHERE user_id = $1;
+
+
+-- name: PinChirp :one
+INSERT INTO pinned_chirps (chirp_id, user_id, pinned_at)
+VALUES ($1, $2, NOW())
+RETURNING *;
+
+
+-- name: UnpinChirp :one
+DELETE FROM pinned_chirps
+WHERE chirp_id = $1
+AND user_id = $2
+RETURNING *;
+
+
+-- name: ListPinnedChirps :many
+-- newest pin first, with the same visibility rules as ListChirps
+SELECT chirps.* FROM pinned_chirps
+JOIN chirps ON chirps.chirp_id = pinned_chirps.chirp_id
+JOIN users ON users.id = chirps.user_id
+WHERE pinned_chirps.user_id = sqlc.arg(author_id)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg(viewer_id))
+AND chirps.deleted_at IS NULL
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = sqlc.arg(viewer_id)
+    AND user_blocks.blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE user_mutes.muter_id = sqlc.arg(viewer_id)
+    AND user_mutes.muted_id = chirps.user_id
+)
+ORDER BY pinned_chirps.pinned_at DESC;
@@ -0,0 +1,55 @@
+-- name: CreatePoll :one
+INSERT INTO polls (chirp_id, created_at, closes_at)
+VALUES ($1, NOW(), $2)
+RETURNING *;
+
+
+-- name: CreatePollOption :one
+INSERT INTO poll_options (id, chirp_id, position, text)
+VALUES (
+    gen_random_uuid(), $1, $2, $3
+)
+RETURNING *;
+
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id = $1;
+
+
+-- name: ListPollsByChirpIDs :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+
+-- name: ListPollOptionsByChirpIDs :many
+SELECT * FROM poll_options
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position ASC;
+
+
+-- name: CountPollVotes :many
+SELECT option_id, COUNT(*) AS votes FROM poll_votes
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY option_id;
+
+
+-- name: ListPollVotesByUser :many
+SELECT * FROM poll_votes
+WHERE user_id = sqlc.arg(user_id)
+AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+
+-- name: CastVote :one
+-- inserts or changes the user's vote in one statement, returns no rows if
+-- the option isn't part of the poll or the poll has closed
+INSERT INTO poll_votes (chirp_id, user_id, option_id, created_at, updated_at)
+SELECT poll_options.chirp_id, sqlc.arg(user_id)::uuid, poll_options.id, NOW(), NOW()
+FROM poll_options
+JOIN polls ON polls.chirp_id = poll_options.chirp_id
+WHERE poll_options.id = sqlc.arg(option_id)::uuid
+AND poll_options.chirp_id = sqlc.arg(chirp_id)::uuid
+AND polls.closes_at > NOW()
+ON CONFLICT (chirp_id, user_id) DO UPDATE SET option_id = EXCLUDED.option_id,
+updated_at = NOW()
+RETURNING *;
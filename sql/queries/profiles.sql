@@ -0,0 +1,18 @@
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE LOWER(handle) = LOWER(sqlc.arg(handle));
+
+
+-- name: UpdateUserProfile :one
+UPDATE users SET handle = sqlc.narg(handle),
+display_name = $2,
+bio = $3,
+avatar_url = $4,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: ListUsersByIDs :many
+SELECT * FROM users
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
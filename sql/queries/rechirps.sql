@@ -0,0 +1,50 @@
+-- name: CreateRechirp :one
+INSERT INTO chirps (chirp_id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), '', $1, $2
+)
+RETURNING *;
+
+
+-- name: UndoRechirp :one
+DELETE FROM chirps
+WHERE user_id = $1
+AND rechirp_of = $2
+AND deleted_at IS NULL
+RETURNING *;
+
+
+-- name: ListEmbeddableChirps :many
+-- the chirps rechirps and quotes point at, as far as everyone may see them
+-- and the viewer hasn't blocked or muted their author
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+AND chirps.hidden_at IS NULL
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = sqlc.arg(viewer_id)
+    AND user_blocks.blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE user_mutes.muter_id = sqlc.arg(viewer_id)
+    AND user_mutes.muted_id = chirps.user_id
+);
+
+
+-- name: CountRechirps :many
+-- only rechirps ListChirps shows to everyone count
+SELECT chirps.rechirp_of, COUNT(*) AS rechirps FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.rechirp_of = ANY(sqlc.arg(chirp_ids)::uuid[])
+AND chirps.hidden_at IS NULL
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+GROUP BY chirps.rechirp_of;
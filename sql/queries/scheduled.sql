@@ -0,0 +1,47 @@
+-- name: ListScheduledChirps :many
+SELECT * FROM chirps
+WHERE user_id = $1
+AND publish_at IS NOT NULL
+AND deleted_at IS NULL
+ORDER BY publish_at ASC;
+
+
+-- name: RescheduleChirp :one
+UPDATE chirps SET publish_at = sqlc.arg(publish_at)::timestamp,
+updated_at = NOW()
+WHERE chirp_id = sqlc.arg(chirp_id)
+AND user_id = sqlc.arg(user_id)
+AND publish_at IS NOT NULL
+AND deleted_at IS NULL
+RETURNING *;
+
+
+-- name: CancelScheduledChirp :one
+DELETE FROM chirps
+WHERE chirp_id = $1
+AND user_id = $2
+AND publish_at IS NOT NULL
+RETURNING *;
+
+
+-- name: PublishDueChirp :one
+-- publishes one due chirp, SKIP LOCKED lets several instances share the work
+-- without publishing anything twice. Chirps of banned, suspended, hidden or
+-- leaving authors stay queued; a suspended author's go out once it ends.
+UPDATE chirps SET publish_at = NULL,
+created_at = NOW(),
+updated_at = NOW()
+WHERE chirp_id = (
+    SELECT chirps.chirp_id FROM chirps
+    JOIN users ON users.id = chirps.user_id
+    WHERE chirps.publish_at <= NOW()
+    AND chirps.deleted_at IS NULL
+    AND users.banned_at IS NULL
+    AND (users.suspended_until IS NULL OR users.suspended_until <= NOW())
+    AND users.deletion_requested_at IS NULL
+    AND users.chirps_hidden = FALSE
+    ORDER BY chirps.publish_at ASC
+    LIMIT 1
+    FOR UPDATE OF chirps SKIP LOCKED
+)
+RETURNING *;
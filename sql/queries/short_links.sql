@@ -0,0 +1,29 @@
+-- name: CreateShortLink :one
+-- a clashing code returns no rows and the caller picks another one
+INSERT INTO short_links (code, created_at, chirp_id, url)
+VALUES (
+    $1, NOW(), $2, $3
+)
+ON CONFLICT (code) DO NOTHING
+RETURNING *;
+
+
+-- name: ListShortLinksByChirpIDs :many
+SELECT * FROM short_links
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+
+-- name: FollowShortLink :one
+-- links only work while everyone may see their chirp, the same rules as
+-- ListEmbeddableChirps
+UPDATE short_links SET clicks = clicks + 1
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE short_links.code = $1
+AND chirps.chirp_id = short_links.chirp_id
+AND chirps.hidden_at IS NULL
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+RETURNING short_links.url;
@@ -1,30 +1,110 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, sqlc.narg(handle)
 )
 RETURNING *;
 
 
 -- name: CreateChirp :one
-INSERT INTO chirps (chirp_id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (chirp_id, created_at, updated_at, body, user_id, publish_at, quote_of)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, sqlc.narg(publish_at), sqlc.narg(quote_of)
 )
 RETURNING *;
 
 
 -- name: ListChirps :many
-SELECT * FROM chirps
-ORDER BY created_at ASC;
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg(viewer_id))
+AND (chirps.publish_at IS NULL OR chirps.user_id = sqlc.arg(viewer_id))
+AND chirps.deleted_at IS NULL
+-- rechirps go away with the chirp they share, by the same rules as
+-- ListEmbeddableChirps
+AND (chirps.rechirp_of IS NULL OR EXISTS (
+    SELECT 1 FROM chirps original
+    JOIN users original_author ON original_author.id = original.user_id
+    WHERE original.chirp_id = chirps.rechirp_of
+    AND original.deleted_at IS NULL
+    AND original.hidden_at IS NULL
+    AND original.publish_at IS NULL
+    AND original_author.chirps_hidden = FALSE
+    AND original_author.deletion_requested_at IS NULL
+))
+AND users.chirps_hidden = FALSE
+AND users.deletion_requested_at IS NULL
+AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id)::uuid)
+-- an author's pinned chirps come from ListPinnedChirps and go first
+AND (sqlc.narg(author_id)::uuid IS NULL OR NOT EXISTS (
+    SELECT 1 FROM pinned_chirps
+    WHERE pinned_chirps.chirp_id = chirps.chirp_id
+))
+-- nothing from people the viewer blocked or muted, including rechirps of them
+AND NOT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_blocks.blocker_id = sqlc.arg(viewer_id)
+    AND user_blocks.blocked_id IN (chirps.user_id, (SELECT original.user_id FROM chirps original WHERE original.chirp_id = chirps.rechirp_of))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM user_mutes
+    WHERE user_mutes.muter_id = sqlc.arg(viewer_id)
+    AND user_mutes.muted_id IN (chirps.user_id, (SELECT original.user_id FROM chirps original WHERE original.chirp_id = chirps.rechirp_of))
+)
+ORDER BY chirps.created_at ASC;
+
+
+
+-- name: IsChirpListed :one
+-- whether ListChirps shows the chirp to everyone, before any viewer's blocks
+-- and mutes
+SELECT EXISTS (
+    SELECT 1 FROM chirps
+    JOIN users ON users.id = chirps.user_id
+    WHERE chirps.chirp_id = $1
+    AND chirps.hidden_at IS NULL
+    AND chirps.publish_at IS NULL
+    AND chirps.deleted_at IS NULL
+    AND (chirps.rechirp_of IS NULL OR EXISTS (
+        SELECT 1 FROM chirps original
+        JOIN users original_author ON original_author.id = original.user_id
+        WHERE original.chirp_id = chirps.rechirp_of
+        AND original.deleted_at IS NULL
+        AND original.hidden_at IS NULL
+        AND original.publish_at IS NULL
+        AND original_author.chirps_hidden = FALSE
+        AND original_author.deletion_requested_at IS NULL
+    ))
+    AND users.chirps_hidden = FALSE
+    AND users.deletion_requested_at IS NULL
+);
+
+
+
+-- name: GetChirpAuthor :one
+-- deleted chirps too, rechirps and quotes of them still name their author
+SELECT user_id FROM chirps
+WHERE chirp_id = $1;
 
 
 
 -- name: GetChirp :one
 SELECT * FROM chirps
+WHERE chirp_id = $1
+AND deleted_at IS NULL;
+
+
+-- name: GetChirpIncludingDeleted :one
+SELECT * FROM chirps
 WHERE chirp_id = $1;
 
 
+-- name: ListChirpsByUserIncludingDeleted :many
+SELECT * FROM chirps
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+
 -- name: GetUser :one
 SELECT * FROM users
 WHERE email = $1;
@@ -62,12 +142,101 @@ RETURNING *;
 
 
 -- name: DeleteChirp :one
-DELETE FROM chirps
+-- deleting a chirp also unpins it
+WITH unpinned AS (
+    DELETE FROM pinned_chirps
+    WHERE pinned_chirps.chirp_id = sqlc.arg(chirp_id)
+)
+UPDATE chirps SET deleted_at = NOW(),
+updated_at = NOW()
+WHERE chirps.chirp_id = sqlc.arg(chirp_id)
+AND chirps.deleted_at IS NULL
+RETURNING *;
+
+
+-- name: RestoreDeletedChirp :one
+UPDATE chirps SET deleted_at = NULL,
+updated_at = NOW()
 WHERE chirp_id = $1
+AND user_id = $2
+AND deleted_at > sqlc.arg(deleted_after)::timestamp
 RETURNING *;
 
 
+-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at < sqlc.arg(deleted_before)::timestamp;
+
+
 -- name: UpgradeChirpyRed :one
 UPDATE users SET is_chirpy_red = TRUE
 WHERE id = $1
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SetUserRole :one
+UPDATE users SET role = $2,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: SuspendUser :one
+UPDATE users SET suspended_until = $2,
+suspension_reason = $3,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: LiftSuspension :one
+UPDATE users SET suspended_until = NULL,
+suspension_reason = '',
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: ListLiveChirpsByUser :many
+-- what live clients currently show of a user, to take down when a ban hides
+-- their chirps
+SELECT * FROM chirps
+WHERE user_id = $1
+AND deleted_at IS NULL
+AND hidden_at IS NULL
+AND publish_at IS NULL;
+
+
+-- name: BanUser :one
+UPDATE users SET banned_at = NOW(),
+ban_reason = $2,
+chirps_hidden = $3,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: UnbanUser :one
+UPDATE users SET banned_at = NULL,
+ban_reason = '',
+chirps_hidden = FALSE,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: ListActiveRefreshTokens :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+AND revoked_at IS NULL
+AND expires_at > NOW();
+
+
+-- name: SetUserRoleByEmail :one
+UPDATE users SET role = $2,
+updated_at = NOW()
+WHERE email = $1
 RETURNING *;
\ No newline at end of file
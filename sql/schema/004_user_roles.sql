@@ -0,0 +1,13 @@
+-- +goose Up
+-- is_chirpy_red was nullable, but the code has always treated it as a plain bool
+UPDATE users SET is_chirpy_red = FALSE WHERE is_chirpy_red IS NULL;
+ALTER TABLE users ALTER COLUMN is_chirpy_red SET DEFAULT FALSE;
+ALTER TABLE users ALTER COLUMN is_chirpy_red SET NOT NULL;
+
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+	CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE users ALTER COLUMN is_chirpy_red DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN is_chirpy_red DROP DEFAULT;
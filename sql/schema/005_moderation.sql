@@ -0,0 +1,37 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN hidden_at TIMESTAMP DEFAULT NULL;
+
+CREATE TABLE reports (
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	chirp_id UUID NOT NULL REFERENCES chirps(chirp_id) ON DELETE CASCADE,
+	reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	reason TEXT NOT NULL
+		CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'misinformation', 'other')),
+	details TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'open'
+		CHECK (status IN ('open', 'dismissed', 'actioned')),
+	resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	resolved_at TIMESTAMP DEFAULT NULL,
+	UNIQUE (chirp_id, reporter_id)
+);
+
+CREATE INDEX reports_status_idx ON reports (status, created_at);
+
+-- the audit trail deliberately has no foreign keys so it outlives the rows it describes
+CREATE TABLE moderation_actions (
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	moderator_id UUID,
+	action TEXT NOT NULL,
+	chirp_id UUID,
+	report_id UUID,
+	target_user_id UUID,
+	reason TEXT NOT NULL DEFAULT ''
+);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+ALTER TABLE chirps DROP COLUMN hidden_at;
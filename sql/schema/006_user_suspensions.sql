@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP DEFAULT NULL;
+ALTER TABLE users ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN banned_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE users ADD COLUMN ban_reason TEXT NOT NULL DEFAULT '';
+-- set when a ban should also remove the user's chirps from every listing
+ALTER TABLE users ADD COLUMN chirps_hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN chirps_hidden;
+ALTER TABLE users DROP COLUMN ban_reason;
+ALTER TABLE users DROP COLUMN banned_at;
+ALTER TABLE users DROP COLUMN suspension_reason;
+ALTER TABLE users DROP COLUMN suspended_until;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_deleted_at_idx;
+ALTER TABLE chirps DROP COLUMN deleted_at;
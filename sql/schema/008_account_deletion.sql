@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN deletion_requested_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE users ADD COLUMN delete_after TIMESTAMP DEFAULT NULL;
+
+CREATE TABLE export_jobs (
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	status TEXT NOT NULL DEFAULT 'pending'
+		CHECK (status IN ('pending', 'running', 'done', 'failed')),
+	file_path TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	completed_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX export_jobs_status_idx ON export_jobs (status, created_at);
+
+-- +goose Down
+DROP TABLE export_jobs;
+ALTER TABLE users DROP COLUMN delete_after;
+ALTER TABLE users DROP COLUMN deletion_requested_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE media (
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	-- NULL until the upload is attached to a chirp, unattached uploads are
+	-- purged after a day
+	chirp_id UUID REFERENCES chirps(chirp_id) ON DELETE SET NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	storage_key TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size_bytes BIGINT NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL
+);
+
+CREATE INDEX media_chirp_id_idx ON media (chirp_id, position);
+
+-- +goose Down
+DROP TABLE media;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- previews are cached per URL and shared by every chirp that links to it
+CREATE TABLE link_previews (
+	url TEXT PRIMARY KEY,
+	fetched_at TIMESTAMP NOT NULL,
+	-- 'ok' or 'failed', failed fetches are cached too so a dead link isn't
+	-- fetched again for every chirp
+	status TEXT NOT NULL,
+	title TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	image_url TEXT NOT NULL DEFAULT '',
+	site_name TEXT NOT NULL DEFAULT ''
+);
+
+-- +goose Down
+DROP TABLE link_previews;
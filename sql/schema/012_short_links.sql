@@ -0,0 +1,13 @@
+-- +goose Up
+-- chirps keep their original links, these are what they are shown as
+CREATE TABLE short_links (
+	code TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	chirp_id UUID NOT NULL REFERENCES chirps(chirp_id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	clicks BIGINT NOT NULL DEFAULT 0,
+	UNIQUE (chirp_id, url)
+);
+
+-- +goose Down
+DROP TABLE short_links;
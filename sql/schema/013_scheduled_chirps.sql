@@ -0,0 +1,9 @@
+-- +goose Up
+-- set while a chirp is waiting to be published, cleared by the scheduler
+ALTER TABLE chirps ADD COLUMN publish_at TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX chirps_publish_at_idx ON chirps (publish_at) WHERE publish_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_publish_at_idx;
+ALTER TABLE chirps DROP COLUMN publish_at;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- drafts live apart from chirps so no chirp query can ever return one
+CREATE TABLE drafts (
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	body TEXT NOT NULL
+);
+
+CREATE INDEX drafts_user_id_idx ON drafts (user_id, updated_at);
+
+-- +goose Down
+DROP TABLE drafts;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- a rechirp is a chirp with an empty body that points at the original, so
+-- it shows up in timelines like any other chirp
+ALTER TABLE chirps ADD COLUMN rechirp_of UUID DEFAULT NULL REFERENCES chirps(chirp_id) ON DELETE CASCADE;
+-- a quote chirp has its own body and embeds the quoted one
+ALTER TABLE chirps ADD COLUMN quote_of UUID DEFAULT NULL REFERENCES chirps(chirp_id) ON DELETE SET NULL;
+
+-- one live rechirp per user and chirp
+CREATE UNIQUE INDEX chirps_rechirp_unique_idx ON chirps (user_id, rechirp_of)
+WHERE rechirp_of IS NOT NULL AND deleted_at IS NULL;
+
+CREATE INDEX chirps_rechirp_of_idx ON chirps (rechirp_of) WHERE rechirp_of IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_rechirp_of_idx;
+DROP INDEX chirps_rechirp_unique_idx;
+ALTER TABLE chirps DROP COLUMN quote_of;
+ALTER TABLE chirps DROP COLUMN rechirp_of;
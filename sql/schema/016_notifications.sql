@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE notifications (
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	-- who the notification is for
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type TEXT NOT NULL,
+	-- who caused it, if anyone
+	actor_id UUID REFERENCES users(id) ON DELETE CASCADE,
+	chirp_id UUID REFERENCES chirps(chirp_id) ON DELETE CASCADE,
+	-- anything else the type needs, e.g. the new Chirpy Red status
+	detail TEXT NOT NULL DEFAULT '',
+	read_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, created_at);
+CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+-- a row here means the user doesn't want notifications of that type
+CREATE TABLE notification_mutes (
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type TEXT NOT NULL,
+	PRIMARY KEY (user_id, type)
+);
+
+-- +goose Down
+DROP TABLE notification_mutes;
+DROP TABLE notifications;
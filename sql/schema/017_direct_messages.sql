@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE conversations (
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	-- bumped on every message, conversations are listed by it
+	updated_at TIMESTAMP NOT NULL,
+	created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+	is_group BOOLEAN NOT NULL
+);
+
+CREATE TABLE conversation_participants (
+	conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	joined_at TIMESTAMP NOT NULL,
+	-- read receipts: everything up to here has been read
+	last_read_at TIMESTAMP DEFAULT NULL,
+	PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX conversation_participants_user_id_idx ON conversation_participants (user_id);
+
+CREATE TABLE messages (
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+	sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	body TEXT NOT NULL
+);
+
+CREATE INDEX messages_conversation_id_idx ON messages (conversation_id, created_at);
+
+-- blocked_id can't message blocker_id
+CREATE TABLE message_blocks (
+	blocker_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	blocked_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (blocker_id, blocked_id)
+);
+
+-- +goose Down
+DROP TABLE message_blocks;
+DROP TABLE messages;
+DROP TABLE conversation_participants;
+DROP TABLE conversations;
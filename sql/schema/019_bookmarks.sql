@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE bookmark_collections (
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE bookmarks (
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	chirp_id UUID NOT NULL REFERENCES chirps(chirp_id) ON DELETE CASCADE,
+	-- deleting a collection leaves its bookmarks uncategorized
+	collection_id UUID REFERENCES bookmark_collections(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX bookmarks_user_id_created_at_idx ON bookmarks (user_id, created_at);
+
+-- +goose Down
+DROP TABLE bookmarks;
+DROP TABLE bookmark_collections;
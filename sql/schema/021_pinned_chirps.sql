@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE pinned_chirps (
+	chirp_id UUID PRIMARY KEY REFERENCES chirps(chirp_id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	pinned_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX pinned_chirps_user_id_idx ON pinned_chirps (user_id);
+
+-- +goose Down
+DROP TABLE pinned_chirps;